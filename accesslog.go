@@ -0,0 +1,181 @@
+package gohttpmw
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// CommonLogFormat is the NCSA Common Log Format
+	CommonLogFormat = `$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent`
+	// CombinedLogFormat is the NCSA Combined Log Format, as written by
+	// Apache and nginx by default
+	CombinedLogFormat = CommonLogFormat + ` "$http_referer" "$http_user_agent"`
+)
+
+// accessLogVariables are the variables of the access log formats,
+// $http_<name> and $sent_http_<name> are the request and response headers
+var accessLogVariables = map[string]func(*accessLogEntry) string{
+	"remote_addr":     func(e *accessLogEntry) string { return clientIP(e.r) },
+	"remote_user":     (*accessLogEntry).remoteUser,
+	"time_local":      func(e *accessLogEntry) string { return e.start.Format("02/Jan/2006:15:04:05 -0700") },
+	"time_iso8601":    func(e *accessLogEntry) string { return e.start.Format(time.RFC3339) },
+	"request":         func(e *accessLogEntry) string { return e.r.Method + " " + e.r.RequestURI + " " + e.r.Proto },
+	"request_method":  func(e *accessLogEntry) string { return e.r.Method },
+	"request_uri":     func(e *accessLogEntry) string { return e.r.RequestURI },
+	"uri":             func(e *accessLogEntry) string { return e.r.URL.Path },
+	"args":            func(e *accessLogEntry) string { return e.r.URL.RawQuery },
+	"server_protocol": func(e *accessLogEntry) string { return e.r.Proto },
+	"scheme":          func(e *accessLogEntry) string { return requestScheme(e.r) },
+	"host":            func(e *accessLogEntry) string { return requestHost(e.r) },
+	"status":          func(e *accessLogEntry) string { return strconv.Itoa(e.naw.httpStatus) },
+	"body_bytes_sent": func(e *accessLogEntry) string { return strconv.Itoa(e.naw.length) },
+	"request_time": func(e *accessLogEntry) string {
+		return strconv.FormatFloat(e.duration.Seconds(), 'f', 3, 64)
+	},
+	"request_id": func(e *accessLogEntry) string { return GetRequestID(e.r.Context()) },
+}
+
+type accessLogEntry struct {
+	r        *http.Request
+	naw      *augmentedResponseWriter
+	start    time.Time
+	duration time.Duration
+}
+
+// remoteUser is the authenticated principal, or the basic auth user
+func (e *accessLogEntry) remoteUser() string {
+	if id := principalID(e.r.Context()); id != "" {
+		return id
+	}
+	user, _, _ := e.r.BasicAuth()
+
+	return user
+}
+
+// accessLogPart is either a literal or a variable of the format
+type accessLogPart struct {
+	literal  string
+	variable func(*accessLogEntry) string
+}
+
+// AccessLog writes a line per request to w in format, such as
+// CommonLogFormat, CombinedLogFormat or a format with the nginx variables
+// $remote_addr, $remote_user, $time_local, $time_iso8601, $request,
+// $request_method, $request_uri, $uri, $args, $server_protocol, $scheme,
+// $host, $status, $body_bytes_sent, $request_time, $request_id,
+// $http_<name> and $sent_http_<name>. Empty values are written as -
+func AccessLog(w io.Writer, format string) (func(http.Handler) http.Handler, error) {
+	parts, err := parseAccessLogFormat(format)
+	if err != nil {
+		return nil, err
+	}
+	var mu sync.Mutex
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			e := accessLogEntry{
+				r:     r,
+				naw:   newAugmentedResponseWriter(rw),
+				start: time.Now(),
+			}
+
+			h.ServeHTTP(e.naw, r)
+
+			e.duration = time.Since(e.start)
+
+			var b strings.Builder
+			for _, p := range parts {
+				if p.variable == nil {
+					b.WriteString(p.literal)
+					continue
+				}
+				writeAccessLogValue(&b, p.variable(&e))
+			}
+			b.WriteByte('\n')
+
+			mu.Lock()
+			_, _ = io.WriteString(w, b.String())
+			mu.Unlock()
+		})
+	}, nil
+}
+
+func parseAccessLogFormat(format string) ([]accessLogPart, error) {
+	var parts []accessLogPart
+	for format != "" {
+		i := strings.IndexByte(format, '$')
+		if i < 0 {
+			parts = append(parts, accessLogPart{literal: format})
+			break
+		}
+		end := i + 1
+		for end < len(format) && isAccessLogNameByte(format[end]) {
+			end++
+		}
+		if end == i+1 {
+			// A lone $ is a literal
+			parts = append(parts, accessLogPart{literal: format[:end]})
+			format = format[end:]
+			continue
+		}
+		if i > 0 {
+			parts = append(parts, accessLogPart{literal: format[:i]})
+		}
+
+		variable, err := accessLogVariable(format[i+1 : end])
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, accessLogPart{variable: variable})
+		format = format[end:]
+	}
+
+	return parts, nil
+}
+
+func accessLogVariable(name string) (func(*accessLogEntry) string, error) {
+	if v, ok := accessLogVariables[name]; ok {
+		return v, nil
+	}
+	if header, ok := strings.CutPrefix(name, "http_"); ok && header != "" {
+		header = http.CanonicalHeaderKey(strings.ReplaceAll(header, "_", "-"))
+		return func(e *accessLogEntry) string {
+			return e.r.Header.Get(header)
+		}, nil
+	}
+	if header, ok := strings.CutPrefix(name, "sent_http_"); ok && header != "" {
+		header = http.CanonicalHeaderKey(strings.ReplaceAll(header, "_", "-"))
+		return func(e *accessLogEntry) string {
+			return e.naw.Header().Get(header)
+		}, nil
+	}
+
+	return nil, fmt.Errorf("accesslog: unknown variable $%s", name)
+}
+
+func isAccessLogNameByte(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+// writeAccessLogValue escapes the quotes, backslashes and control
+// characters of v as nginx does, so that a line can't be forged
+func writeAccessLogValue(b *strings.Builder, v string) {
+	if v == "" {
+		b.WriteByte('-')
+		return
+	}
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		if c == '"' || c == '\\' || c < 0x20 || c >= 0x7f {
+			fmt.Fprintf(b, `\x%02X`, c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+}
@@ -0,0 +1,87 @@
+package gohttpmw
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestAccessLog(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("hello"))
+	})
+
+	tests := []struct {
+		name     string
+		format   string
+		request  func() *http.Request
+		expected string
+	}{
+		{
+			name:   "common",
+			format: CommonLogFormat,
+			request: func() *http.Request {
+				r := httptest.NewRequest(http.MethodPost, "/fish?name=nemo", nil)
+				r.SetBasicAuth("pollux", "s3cr3t")
+				return r
+			},
+			expected: `^192\.0\.2\.1 - pollux \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] ` +
+				`"POST /fish\?name=nemo HTTP/1\.1" 201 5\n$`,
+		},
+		{
+			name:   "combined",
+			format: CombinedLogFormat,
+			request: func() *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/", nil)
+				r.Header.Set("User-Agent", `curl/8.0 "evil"`)
+				return r
+			},
+			expected: `^192\.0\.2\.1 - - \[.+\] "GET / HTTP/1\.1" 201 5 "-" "curl/8\.0 \\x22evil\\x22"\n$`,
+		},
+		{
+			name:   "custom",
+			format: `$scheme://$host$uri?$args $status $request_time $sent_http_content_type cost 5$`,
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/fish?name=nemo", nil)
+			},
+			expected: `^http://example\.com/fish\?name=nemo 201 \d+\.\d{3} text/plain cost 5\$\n$`,
+		},
+		{
+			name:   "request id and principal",
+			format: `$request_id $remote_user`,
+			request: func() *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/", nil)
+				*r = *r.WithContext(WithPrincipal(r.Context(), &Principal{ID: "castor"}))
+				return r
+			},
+			expected: `^[0-9a-v]{20} castor\n$`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			mw, err := AccessLog(&buf, tt.format)
+			if err != nil {
+				t.Fatalf("AccessLog failed %v", err)
+			}
+			rr := httptest.NewRecorder()
+			NewChain(RequestID(), mw).Then(handler).ServeHTTP(rr, tt.request())
+
+			if !regexp.MustCompile(tt.expected).MatchString(buf.String()) {
+				t.Errorf("expected %s, got %q", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestAccessLogFormatError(t *testing.T) {
+	for _, format := range []string{"$status $bytes", "$http_"} {
+		if _, err := AccessLog(&bytes.Buffer{}, format); err == nil {
+			t.Errorf("%s: expected an error", format)
+		}
+	}
+}
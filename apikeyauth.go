@@ -0,0 +1,131 @@
+package gohttpmw
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+const (
+	// ContextKeyAPIKey allow storage of the authenticated api key
+	// in the context
+	ContextKeyAPIKey = ContextKey("apiKey")
+)
+
+// Errors returned when an api key is rejected
+var (
+	ErrAPIKeyMissing = errors.New("apikey: missing api key")
+	ErrAPIKeyInvalid = errors.New("apikey: invalid api key")
+)
+
+// APIKey describes the owner of an api key, it never holds the key itself
+type APIKey struct {
+	ID        string
+	Principal string
+	Roles     []string
+}
+
+// APIKeyOption configures the APIKeyAuth middleware
+type APIKeyOption func(*apiKeyConfig)
+
+type apiKeyConfig struct {
+	header     string
+	queryParam string
+}
+
+// WithAPIKeyHeader reads the api key from the named header,
+// X-API-Key by default
+func WithAPIKeyHeader(name string) APIKeyOption {
+	return func(c *apiKeyConfig) {
+		c.header = name
+	}
+}
+
+// WithAPIKeyQuery also reads the api key from the named query parameter
+// when the header is absent
+func WithAPIKeyQuery(param string) APIKeyOption {
+	return func(c *apiKeyConfig) {
+		c.queryParam = param
+	}
+}
+
+// APIKeyAuth authenticates the request with an api key checked against
+// the store, the key's owner and the matching principal are put in the
+// context and the key id is added to the request log,
+// requests without a valid key get a 401
+func APIKeyAuth(store KeyStore, opts ...APIKeyOption) func(http.Handler) http.Handler {
+	cfg := apiKeyConfig{header: "X-API-Key"}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(h http.Handler) http.Handler {
+		next := AddToRequestLog("api_key_id", apiKeyID)(h)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(cfg.header)
+			if key == "" && cfg.queryParam != "" {
+				key = r.URL.Query().Get(cfg.queryParam)
+			}
+			if key == "" {
+				SetRequestError(r, ErrAPIKeyMissing)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			apiKey, err := store.Lookup(key)
+			if err != nil {
+				SetRequestError(r, err)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), ContextKeyAPIKey, apiKey)
+			ctx = WithPrincipal(ctx, &Principal{
+				ID:         apiKey.Principal,
+				Roles:      apiKey.Roles,
+				AuthMethod: AuthMethodAPIKey,
+				Attributes: map[string]interface{}{"api_key_id": apiKey.ID},
+			})
+			// We don't want to lose the reference to the Request
+			*r = *r.WithContext(ctx)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// GetAPIKey will retrieve the authenticated api key
+// from the context if there is one
+func GetAPIKey(ctx context.Context) *APIKey {
+	if apiKey, ok := ctx.Value(ContextKeyAPIKey).(*APIKey); ok {
+		return apiKey
+	}
+
+	return nil
+}
+
+// APIKeyPrincipal is a role extractor for RBAC using the key's principal
+func APIKeyPrincipal(ctx context.Context) string {
+	if apiKey := GetAPIKey(ctx); apiKey != nil {
+		return apiKey.Principal
+	}
+
+	return ""
+}
+
+// APIKeyRole is a role extractor for RBAC using the key's first role
+func APIKeyRole(ctx context.Context) string {
+	if apiKey := GetAPIKey(ctx); apiKey != nil && len(apiKey.Roles) > 0 {
+		return apiKey.Roles[0]
+	}
+
+	return ""
+}
+
+func apiKeyID(ctx context.Context) interface{} {
+	if apiKey := GetAPIKey(ctx); apiKey != nil {
+		return apiKey.ID
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package gohttpmw
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPIKeyAuth(t *testing.T) {
+	store := NewMemoryKeyStore(map[string]APIKey{
+		"s3cr3t": {ID: "key1", Principal: "billing", Roles: []string{"admin"}},
+	})
+
+	tests := []struct {
+		name              string
+		opts              []APIKeyOption
+		header            string
+		headerValue       string
+		url               string
+		expectedStatus    int
+		expectedErr       error
+		expectedPrincipal string
+	}{
+		{
+			name:              "valid key in default header",
+			header:            "X-API-Key",
+			headerValue:       "s3cr3t",
+			url:               "/",
+			expectedStatus:    http.StatusOK,
+			expectedPrincipal: "billing",
+		},
+		{
+			name:              "valid key in custom header",
+			opts:              []APIKeyOption{WithAPIKeyHeader("X-Token")},
+			header:            "X-Token",
+			headerValue:       "s3cr3t",
+			url:               "/",
+			expectedStatus:    http.StatusOK,
+			expectedPrincipal: "billing",
+		},
+		{
+			name:              "valid key in query",
+			opts:              []APIKeyOption{WithAPIKeyQuery("api_key")},
+			url:               "/?api_key=s3cr3t",
+			expectedStatus:    http.StatusOK,
+			expectedPrincipal: "billing",
+		},
+		{
+			name:           "query ignored when not enabled",
+			url:            "/?api_key=s3cr3t",
+			expectedStatus: http.StatusUnauthorized,
+			expectedErr:    ErrAPIKeyMissing,
+		},
+		{
+			name:           "invalid key",
+			header:         "X-API-Key",
+			headerValue:    "wrong",
+			url:            "/",
+			expectedStatus: http.StatusUnauthorized,
+			expectedErr:    ErrAPIKeyInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var principal, role string
+			var p *Principal
+			fakeHandler := http.HandlerFunc(
+				func(w http.ResponseWriter, req *http.Request) {
+					principal = APIKeyPrincipal(req.Context())
+					role = APIKeyRole(req.Context())
+					p = GetPrincipal(req.Context())
+				},
+			)
+			midWared := APIKeyAuth(store, tt.opts...)(fakeHandler)
+			rr := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.header != "" {
+				request.Header.Set(tt.header, tt.headerValue)
+			}
+			midWared.ServeHTTP(rr, request)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if err := GetRequestError(request.Context()); err != tt.expectedErr {
+				t.Errorf("expected request error %v, got %v", tt.expectedErr, err)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			if principal != tt.expectedPrincipal || role != "admin" {
+				t.Errorf(
+					"expected principal %s and role admin, got %s and %s",
+					tt.expectedPrincipal, principal, role,
+				)
+			}
+			if p == nil || p.ID != tt.expectedPrincipal ||
+				p.AuthMethod != AuthMethodAPIKey || len(p.Roles) != 1 {
+				t.Errorf("expected principal in context, got %+v", p)
+			}
+			atl := GetAddToRequestLog(request.Context())
+			if atl["api_key_id"] != "key1" {
+				t.Errorf("expected api_key_id in request log, got %v", atl)
+			}
+			for _, v := range atl {
+				if v == "s3cr3t" {
+					t.Errorf("the api key must never be logged")
+				}
+			}
+		})
+	}
+}
+
+func TestGetAPIKey(t *testing.T) {
+	ctx := context.Background()
+	if apiKey := GetAPIKey(ctx); apiKey != nil {
+		t.Errorf("expected nothing, got %v", apiKey)
+	}
+	if p := APIKeyPrincipal(ctx); p != "" {
+		t.Errorf("expected no principal, got %s", p)
+	}
+	if r := APIKeyRole(ctx); r != "" {
+		t.Errorf("expected no role, got %s", r)
+	}
+
+	testKey := &APIKey{ID: "key1"}
+	if apiKey := GetAPIKey(
+		context.WithValue(ctx, ContextKeyAPIKey, testKey),
+	); apiKey != testKey {
+		t.Errorf("expected %v, got %v", testKey, apiKey)
+	}
+}
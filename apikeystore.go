@@ -0,0 +1,110 @@
+package gohttpmw
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeyStore finds the owner of an api key,
+// ErrAPIKeyInvalid is returned for unknown keys
+type KeyStore interface {
+	Lookup(key string) (*APIKey, error)
+}
+
+type hashedAPIKey struct {
+	hash   [sha256.Size]byte
+	apiKey APIKey
+}
+
+// HashedKeyStore is a KeyStore holding only the sha256 of the keys,
+// a lookup compares the key against every entry in constant time
+type HashedKeyStore struct {
+	keys []hashedAPIKey
+}
+
+// NewMemoryKeyStore creates a KeyStore from plain keys,
+// only their hashes are retained
+func NewMemoryKeyStore(keys map[string]APIKey) *HashedKeyStore {
+	s := &HashedKeyStore{}
+	for key, apiKey := range keys {
+		s.keys = append(s.keys, hashedAPIKey{
+			hash:   sha256.Sum256([]byte(key)),
+			apiKey: apiKey,
+		})
+	}
+
+	return s
+}
+
+// NewFileKeyStore loads a KeyStore from the named file, each line
+// is `id:sha256 hex of the key:principal:comma separated roles`,
+// empty lines and lines starting with # are ignored
+func NewFileKeyStore(name string) (*HashedKeyStore, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	s := &HashedKeyStore{}
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, ":")
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("apikey: %s:%d: expected 4 fields", name, lineNum)
+		}
+		hash, err := hex.DecodeString(fields[1])
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("apikey: %s:%d: invalid sha256", name, lineNum)
+		}
+
+		k := hashedAPIKey{apiKey: APIKey{ID: fields[0], Principal: fields[2]}}
+		copy(k.hash[:], hash)
+		if fields[3] != "" {
+			k.apiKey.Roles = strings.Split(fields[3], ",")
+		}
+		s.keys = append(s.keys, k)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Lookup returns a copy of the owner of key
+func (s *HashedKeyStore) Lookup(key string) (*APIKey, error) {
+	hash := sha256.Sum256([]byte(key))
+
+	found := -1
+	for i := range s.keys {
+		// No early exit so that the lookup time doesn't depend on the key
+		if subtle.ConstantTimeCompare(hash[:], s.keys[i].hash[:]) == 1 {
+			found = i
+		}
+	}
+	if found < 0 {
+		return nil, ErrAPIKeyInvalid
+	}
+
+	apiKey := s.keys[found].apiKey
+
+	return &apiKey, nil
+}
+
+// HashAPIKey returns the sha256 hex of key, as expected by NewFileKeyStore
+func HashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,89 @@
+package gohttpmw
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNewFileKeyStore(t *testing.T) {
+	content := "# id:sha256:principal:roles\n\n" +
+		"key1:" + HashAPIKey("s3cr3t") + ":billing:admin,viewer\n" +
+		"key2:" + HashAPIKey("other") + ":reports:\n"
+	name := filepath.Join(t.TempDir(), "apikeys")
+	if err := os.WriteFile(name, []byte(content), 0600); err != nil {
+		t.Fatalf("writing key file failed %v", err)
+	}
+
+	store, err := NewFileKeyStore(name)
+	if err != nil {
+		t.Fatalf("NewFileKeyStore failed %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		key         string
+		expected    *APIKey
+		expectedErr error
+	}{
+		{
+			name: "key with roles",
+			key:  "s3cr3t",
+			expected: &APIKey{
+				ID: "key1", Principal: "billing", Roles: []string{"admin", "viewer"},
+			},
+		},
+		{
+			name:     "key without roles",
+			key:      "other",
+			expected: &APIKey{ID: "key2", Principal: "reports"},
+		},
+		{
+			name:        "unknown key",
+			key:         "unknown",
+			expectedErr: ErrAPIKeyInvalid,
+		},
+		{
+			name:        "hash is not a key",
+			key:         HashAPIKey("s3cr3t"),
+			expectedErr: ErrAPIKeyInvalid,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.Lookup(tt.key)
+			if err != tt.expectedErr {
+				t.Fatalf("expected error %v, got %v", tt.expectedErr, err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestNewFileKeyStoreErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "missing fields", content: "key1:abc\n"},
+		{name: "invalid hash", content: "key1:notahash:billing:admin\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := filepath.Join(t.TempDir(), "apikeys")
+			if err := os.WriteFile(name, []byte(tt.content), 0600); err != nil {
+				t.Fatalf("writing key file failed %v", err)
+			}
+			if _, err := NewFileKeyStore(name); err == nil {
+				t.Errorf("expected an error, got nothing")
+			}
+		})
+	}
+
+	if _, err := NewFileKeyStore(filepath.Join(t.TempDir(), "none")); err == nil {
+		t.Errorf("expected an error for a missing file, got nothing")
+	}
+}
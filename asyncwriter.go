@@ -0,0 +1,176 @@
+package gohttpmw
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrAsyncWriterClosed is returned by the writes to a closed AsyncWriter
+var ErrAsyncWriterClosed = errors.New("asyncwriter: closed")
+
+// AsyncPolicy is what an AsyncWriter does with a write when its buffer is full
+type AsyncPolicy int
+
+const (
+	// AsyncDrop drops the write and counts it, the request goes on
+	AsyncDrop AsyncPolicy = iota
+	// AsyncBlock waits for room in the buffer, the request waits
+	AsyncBlock
+)
+
+// AsyncWriter writes to an io.Writer from a background goroutine,
+// the writes are kept in a bounded ring buffer until then
+type AsyncWriter struct {
+	w      io.Writer
+	policy AsyncPolicy
+
+	mu       sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	idle     *sync.Cond
+	ring     [][]byte
+	head     int
+	count    int
+	writing  bool
+	closed   bool
+	done     chan struct{}
+
+	dropped     uint64
+	writeErrors uint64
+}
+
+// AsyncWriterOption configures an AsyncWriter
+type AsyncWriterOption func(*AsyncWriter)
+
+// WithAsyncBufferSize sets how many writes the buffer holds, 1024 by default
+func WithAsyncBufferSize(n int) AsyncWriterOption {
+	return func(a *AsyncWriter) {
+		a.ring = make([][]byte, max(n, 1))
+	}
+}
+
+// WithAsyncPolicy sets what happens to the writes when the buffer is full,
+// AsyncDrop by default
+func WithAsyncPolicy(p AsyncPolicy) AsyncWriterOption {
+	return func(a *AsyncWriter) {
+		a.policy = p
+	}
+}
+
+// NewAsyncWriter starts the background goroutine writing to w
+func NewAsyncWriter(w io.Writer, opts ...AsyncWriterOption) *AsyncWriter {
+	a := &AsyncWriter{
+		w:    w,
+		ring: make([][]byte, 1024),
+		done: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	a.notEmpty = sync.NewCond(&a.mu)
+	a.notFull = sync.NewCond(&a.mu)
+	a.idle = sync.NewCond(&a.mu)
+
+	go a.run()
+
+	return a
+}
+
+// Write buffers a copy of p, when the buffer is full it is dropped
+// or waits for room depending on the policy
+func (a *AsyncWriter) Write(p []byte) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for !a.closed && a.count == len(a.ring) {
+		if a.policy == AsyncDrop {
+			atomic.AddUint64(&a.dropped, 1)
+			return len(p), nil
+		}
+		a.notFull.Wait()
+	}
+	if a.closed {
+		return 0, ErrAsyncWriterClosed
+	}
+
+	a.ring[(a.head+a.count)%len(a.ring)] = append([]byte(nil), p...)
+	a.count++
+	a.notEmpty.Signal()
+
+	return len(p), nil
+}
+
+// Dropped returns the number of writes dropped because the buffer was full
+func (a *AsyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&a.dropped)
+}
+
+// WriteErrors returns the number of writes that failed
+// on the underlying writer
+func (a *AsyncWriter) WriteErrors() uint64 {
+	return atomic.LoadUint64(&a.writeErrors)
+}
+
+// Flush waits for the buffered writes to be written
+func (a *AsyncWriter) Flush() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for a.count > 0 || a.writing {
+		a.idle.Wait()
+	}
+}
+
+// Close writes the buffered writes and stops the background goroutine,
+// the underlying writer is left open
+func (a *AsyncWriter) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return ErrAsyncWriterClosed
+	}
+	a.closed = true
+	a.notEmpty.Broadcast()
+	a.notFull.Broadcast()
+	a.mu.Unlock()
+
+	<-a.done
+
+	return nil
+}
+
+func (a *AsyncWriter) run() {
+	defer close(a.done)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for {
+		for a.count == 0 && !a.closed {
+			a.notEmpty.Wait()
+		}
+		if a.count == 0 {
+			return
+		}
+
+		p := a.ring[a.head]
+		a.ring[a.head] = nil
+		a.head = (a.head + 1) % len(a.ring)
+		a.count--
+		a.writing = true
+		a.notFull.Signal()
+		a.mu.Unlock()
+
+		if _, err := a.w.Write(p); err != nil {
+			atomic.AddUint64(&a.writeErrors, 1)
+		}
+
+		a.mu.Lock()
+		a.writing = false
+		if a.count == 0 {
+			a.idle.Broadcast()
+		}
+	}
+}
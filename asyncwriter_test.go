@@ -0,0 +1,131 @@
+package gohttpmw
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingWriter holds the writes until release is closed
+type blockingWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.buf.Write(p)
+}
+
+func (w *blockingWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.buf.String()
+}
+
+func TestAsyncWriterPolicies(t *testing.T) {
+	tests := []struct {
+		name            string
+		policy          AsyncPolicy
+		expectedDropped uint64
+		expected        string
+	}{
+		{
+			name:            "drop",
+			policy:          AsyncDrop,
+			expectedDropped: 2,
+			expected:        "abc",
+		},
+		{
+			name:     "block",
+			policy:   AsyncBlock,
+			expected: "abcde",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &blockingWriter{release: make(chan struct{})}
+			aw := NewAsyncWriter(w, WithAsyncBufferSize(2), WithAsyncPolicy(tt.policy))
+
+			_, _ = aw.Write([]byte("a"))
+			// Wait for a to be taken by the background goroutine
+			for {
+				aw.mu.Lock()
+				writing := aw.writing
+				aw.mu.Unlock()
+				if writing {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+
+			written := make(chan struct{})
+			go func() {
+				defer close(written)
+				for _, p := range []string{"b", "c", "d", "e"} {
+					_, _ = aw.Write([]byte(p))
+				}
+			}()
+			if tt.policy == AsyncBlock {
+				select {
+				case <-written:
+					t.Fatalf("expected the writes to block")
+				case <-time.After(20 * time.Millisecond):
+				}
+			}
+			close(w.release)
+			<-written
+
+			aw.Flush()
+			if got := w.String(); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+			if aw.Dropped() != tt.expectedDropped {
+				t.Errorf("expected %d dropped, got %d", tt.expectedDropped, aw.Dropped())
+			}
+			if err := aw.Close(); err != nil {
+				t.Errorf("Close failed %v", err)
+			}
+		})
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestAsyncWriterClose(t *testing.T) {
+	var buf bytes.Buffer
+	aw := NewAsyncWriter(&buf)
+	for i := 0; i < 100; i++ {
+		_, _ = aw.Write([]byte("x"))
+	}
+	if err := aw.Close(); err != nil {
+		t.Fatalf("Close failed %v", err)
+	}
+	if buf.Len() != 100 {
+		t.Errorf("expected the buffered writes to be written on close, got %d", buf.Len())
+	}
+	if _, err := aw.Write([]byte("x")); err != ErrAsyncWriterClosed {
+		t.Errorf("expected %v, got %v", ErrAsyncWriterClosed, err)
+	}
+	if err := aw.Close(); err != ErrAsyncWriterClosed {
+		t.Errorf("expected %v, got %v", ErrAsyncWriterClosed, err)
+	}
+
+	aw = NewAsyncWriter(failingWriter{})
+	_, _ = aw.Write([]byte("x"))
+	_ = aw.Close()
+	if aw.WriteErrors() != 1 {
+		t.Errorf("expected 1 write error, got %d", aw.WriteErrors())
+	}
+}
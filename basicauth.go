@@ -0,0 +1,51 @@
+package gohttpmw
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Errors returned when basic credentials are rejected
+var (
+	ErrBasicAuthMissing = errors.New("basicauth: missing credentials")
+	ErrBasicAuthInvalid = errors.New("basicauth: invalid credentials")
+)
+
+// BasicAuthValidator checks a user name and password and returns
+// the matching principal, ErrBasicAuthInvalid is returned
+// for wrong credentials
+type BasicAuthValidator interface {
+	Validate(user, password string) (*Principal, error)
+}
+
+// BasicAuth authenticates the request with http basic auth and puts
+// the principal in the context, requests without valid credentials
+// get a 401 challenge for realm
+func BasicAuth(realm string, v BasicAuthValidator) func(http.Handler) http.Handler {
+	challenge := fmt.Sprintf(`Basic realm=%q, charset="UTF-8"`, realm)
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, password, ok := r.BasicAuth()
+			if !ok {
+				SetRequestError(r, ErrBasicAuthMissing)
+				w.Header().Set("WWW-Authenticate", challenge)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			p, err := v.Validate(user, password)
+			if err != nil {
+				SetRequestError(r, err)
+				w.Header().Set("WWW-Authenticate", challenge)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			// We don't want to lose the reference to the Request
+			*r = *r.WithContext(WithPrincipal(r.Context(), p))
+			h.ServeHTTP(w, r)
+		})
+	}
+}
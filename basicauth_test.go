@@ -0,0 +1,89 @@
+package gohttpmw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBasicAuth(t *testing.T) {
+	validator := basicAuthValidatorFunc(
+		func(user, password string) (*Principal, error) {
+			if user != "pollux" || password != "s3cr3t" {
+				return nil, ErrBasicAuthInvalid
+			}
+			return &Principal{ID: user, Roles: []string{"admin"}}, nil
+		},
+	)
+
+	tests := []struct {
+		name           string
+		user           string
+		password       string
+		withAuth       bool
+		expectedStatus int
+		expectedErr    error
+	}{
+		{
+			name:           "valid credentials",
+			user:           "pollux",
+			password:       "s3cr3t",
+			withAuth:       true,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "wrong password",
+			user:           "pollux",
+			password:       "wrong",
+			withAuth:       true,
+			expectedStatus: http.StatusUnauthorized,
+			expectedErr:    ErrBasicAuthInvalid,
+		},
+		{
+			name:           "missing credentials",
+			expectedStatus: http.StatusUnauthorized,
+			expectedErr:    ErrBasicAuthMissing,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var role string
+			fakeHandler := http.HandlerFunc(
+				func(w http.ResponseWriter, req *http.Request) {
+					role = PrincipalRole(req.Context())
+				},
+			)
+			midWared := BasicAuth("admin", validator)(fakeHandler)
+			rr := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, `/`, nil)
+			if tt.withAuth {
+				request.SetBasicAuth(tt.user, tt.password)
+			}
+			midWared.ServeHTTP(rr, request)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if err := GetRequestError(request.Context()); err != tt.expectedErr {
+				t.Errorf("expected request error %v, got %v", tt.expectedErr, err)
+			}
+			if tt.expectedStatus == http.StatusOK {
+				if role != "admin" {
+					t.Errorf("expected role admin from the principal, got %s", role)
+				}
+				return
+			}
+			expectedChallenge := `Basic realm="admin", charset="UTF-8"`
+			if got := rr.Header().Get("WWW-Authenticate"); got != expectedChallenge {
+				t.Errorf("expected challenge %q, got %q", expectedChallenge, got)
+			}
+		})
+	}
+}
+
+type basicAuthValidatorFunc func(user, password string) (*Principal, error)
+
+func (f basicAuthValidatorFunc) Validate(user, password string) (*Principal, error) {
+	return f(user, password)
+}
@@ -0,0 +1,168 @@
+package gohttpmw
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrBodyTooLarge is recorded as the request error of the requests
+	// with a body over the limit, and returned when reading past it
+	ErrBodyTooLarge = errors.New("request body too large")
+	// ErrUnsupportedMediaType is recorded as the request error of the requests
+	// with a body of a content type that is not allowed
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+)
+
+// BodyLimitOption configures the BodyLimit middleware
+type BodyLimitOption func(*bodyLimitConfig)
+
+type bodyLimitConfig struct {
+	// limits per media type, "type/*" matches all the subtypes
+	limits  map[string]int64
+	allowed []string
+	route   func(*http.Request) int64
+}
+
+// WithContentTypeLimit sets the limit of the bodies of mediaType,
+// such as "application/json" or "image/*"
+func WithContentTypeLimit(mediaType string, n int64) BodyLimitOption {
+	return func(c *bodyLimitConfig) {
+		c.limits[strings.ToLower(mediaType)] = n
+	}
+}
+
+// WithRouteLimit gives a limit per request, it takes precedence over the
+// content type limits. Returning 0 keeps the other limits and a negative
+// limit disables it
+func WithRouteLimit(f func(*http.Request) int64) BodyLimitOption {
+	return func(c *bodyLimitConfig) {
+		c.route = f
+	}
+}
+
+// WithAllowedContentTypes rejects the bodies of the other media types
+// with a 415, "type/*" allows all the subtypes
+func WithAllowedContentTypes(mediaTypes ...string) BodyLimitOption {
+	return func(c *bodyLimitConfig) {
+		for _, mt := range mediaTypes {
+			c.allowed = append(c.allowed, strings.ToLower(mt))
+		}
+	}
+}
+
+// BodyLimit limits request bodies to max bytes by default. Requests
+// announcing a larger Content-Length get a 413 right away, other bodies
+// such as chunked ones fail with ErrBodyTooLarge when read past the limit,
+// and the handler is expected to respond with a 413.
+// Both cases are recorded as request errors
+func BodyLimit(max int64, opts ...BodyLimitOption) func(http.Handler) http.Handler {
+	cfg := bodyLimitConfig{limits: make(map[string]int64)}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if err != nil {
+				mediaType = ""
+			}
+			if len(cfg.allowed) > 0 && !matchMediaType(cfg.allowed, mediaType) {
+				SetRequestError(r, ErrUnsupportedMediaType)
+				w.WriteHeader(http.StatusUnsupportedMediaType)
+				return
+			}
+
+			limit := cfg.limit(r, mediaType, max)
+			if limit < 0 {
+				h.ServeHTTP(w, r)
+				return
+			}
+			if r.ContentLength > limit {
+				SetRequestError(r, ErrBodyTooLarge)
+				w.Header().Set("Connection", "close")
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			body := &limitedBody{ReadCloser: r.Body, remaining: limit}
+			r.Body = body
+
+			h.ServeHTTP(w, r)
+
+			if body.exceeded {
+				SetRequestError(r, ErrBodyTooLarge)
+			}
+		})
+	}
+}
+
+func (c *bodyLimitConfig) limit(r *http.Request, mediaType string, max int64) int64 {
+	if c.route != nil {
+		if limit := c.route(r); limit != 0 {
+			return limit
+		}
+	}
+	if limit, ok := c.limits[mediaType]; ok {
+		return limit
+	}
+	if i := strings.IndexByte(mediaType, '/'); i > 0 {
+		if limit, ok := c.limits[mediaType[:i]+"/*"]; ok {
+			return limit
+		}
+	}
+
+	return max
+}
+
+func matchMediaType(patterns []string, mediaType string) bool {
+	for _, p := range patterns {
+		if p == mediaType {
+			return true
+		}
+		if strings.HasSuffix(p, "/*") &&
+			strings.HasPrefix(mediaType, strings.TrimSuffix(p, "*")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// limitedBody fails with ErrBodyTooLarge once more than remaining bytes
+// are read, unlike io.LimitReader which would silently truncate the body
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	exceeded  bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.exceeded {
+		return 0, ErrBodyTooLarge
+	}
+	// Read one byte more than allowed to tell a body of exactly
+	// the limit from a larger one
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) > b.remaining {
+		b.exceeded = true
+		n = int(b.remaining)
+		b.remaining = 0
+		return n, ErrBodyTooLarge
+	}
+	b.remaining -= int64(n)
+
+	return n, err
+}
@@ -0,0 +1,151 @@
+package gohttpmw
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBodyLimit(t *testing.T) {
+	fakeHandler := http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			if _, err := io.ReadAll(req.Body); err != nil {
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+			}
+		},
+	)
+	midWared := BodyLimit(
+		10,
+		WithContentTypeLimit("image/*", 20),
+		WithContentTypeLimit("image/svg+xml", 5),
+		WithAllowedContentTypes("application/json", "image/*"),
+		WithRouteLimit(func(r *http.Request) int64 {
+			if strings.HasPrefix(r.URL.Path, "/upload") {
+				return -1
+			}
+			return 0
+		}),
+	)(fakeHandler)
+
+	tests := []struct {
+		name           string
+		path           string
+		contentType    string
+		body           string
+		chunked        bool
+		expectedStatus int
+		expectedErr    error
+	}{
+		{
+			name:           "no body",
+			path:           "/",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "body at the limit",
+			path:           "/",
+			contentType:    "application/json; charset=utf-8",
+			body:           "0123456789",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "body over the limit",
+			path:           "/",
+			contentType:    "application/json",
+			body:           "0123456789a",
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedErr:    ErrBodyTooLarge,
+		},
+		{
+			name:           "chunked body over the limit",
+			path:           "/",
+			contentType:    "application/json",
+			body:           "0123456789a",
+			chunked:        true,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedErr:    ErrBodyTooLarge,
+		},
+		{
+			name:           "chunked body at the limit",
+			path:           "/",
+			contentType:    "application/json",
+			body:           "0123456789",
+			chunked:        true,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "content type wildcard limit",
+			path:           "/",
+			contentType:    "image/png",
+			body:           strings.Repeat("a", 20),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "content type exact limit",
+			path:           "/",
+			contentType:    "image/svg+xml",
+			body:           strings.Repeat("a", 6),
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedErr:    ErrBodyTooLarge,
+		},
+		{
+			name:           "content type not allowed",
+			path:           "/",
+			contentType:    "text/plain",
+			body:           "a",
+			expectedStatus: http.StatusUnsupportedMediaType,
+			expectedErr:    ErrUnsupportedMediaType,
+		},
+		{
+			name:           "route without limit",
+			path:           "/upload",
+			contentType:    "application/json",
+			body:           strings.Repeat("a", 100),
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			var body io.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+			request := httptest.NewRequest(http.MethodPost, tt.path, body)
+			if tt.chunked {
+				request.ContentLength = -1
+			}
+			if tt.contentType != "" {
+				request.Header.Set("Content-Type", tt.contentType)
+			}
+			midWared.ServeHTTP(rr, request)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if err := GetRequestError(request.Context()); err != tt.expectedErr {
+				t.Errorf("expected request error %v, got %v", tt.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestLimitedBody(t *testing.T) {
+	body := &limitedBody{
+		ReadCloser: io.NopCloser(strings.NewReader("0123456789")),
+		remaining:  4,
+	}
+	b, err := io.ReadAll(body)
+	if err != ErrBodyTooLarge {
+		t.Errorf("expected %v, got %v", ErrBodyTooLarge, err)
+	}
+	if string(b) != "0123" {
+		t.Errorf("expected the body to be read up to the limit, got %q", b)
+	}
+	if !body.exceeded {
+		t.Errorf("expected the body to be marked as exceeded")
+	}
+}
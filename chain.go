@@ -0,0 +1,196 @@
+package gohttpmw
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// Middleware is the signature shared by all the middlewares
+type Middleware = func(http.Handler) http.Handler
+
+// Chain is an immutable list of middlewares, the first one is the outermost
+type Chain struct {
+	mws []Middleware
+}
+
+// NewChain creates a chain of mws, the first one handles the request first.
+// The order isn't checked, see NewCheckedChain
+func NewChain(mws ...Middleware) Chain {
+	return Chain{mws: append([]Middleware(nil), mws...)}
+}
+
+// NewCheckedChain creates a chain of mws like NewChain and validates
+// its order against DefaultOrderRules
+func NewCheckedChain(mws ...Middleware) (Chain, error) {
+	c := NewChain(mws...)
+	if err := c.Validate(); err != nil {
+		return Chain{}, err
+	}
+
+	return c, nil
+}
+
+// MustChain is NewCheckedChain panicking when the order is invalid,
+// for the chains built once at startup
+func MustChain(mws ...Middleware) Chain {
+	c, err := NewCheckedChain(mws...)
+	if err != nil {
+		panic(err)
+	}
+
+	return c
+}
+
+// Append returns a new chain with mws added after the middlewares of c
+func (c Chain) Append(mws ...Middleware) Chain {
+	newMws := make([]Middleware, 0, len(c.mws)+len(mws))
+	newMws = append(newMws, c.mws...)
+	newMws = append(newMws, mws...)
+
+	return Chain{mws: newMws}
+}
+
+// Extend returns a new chain with the middlewares of other
+// added after the middlewares of c
+func (c Chain) Extend(other Chain) Chain {
+	return c.Append(other.mws...)
+}
+
+// Then wraps h with the middlewares of the chain,
+// a nil h is http.DefaultServeMux
+func (c Chain) Then(h http.Handler) http.Handler {
+	if h == nil {
+		h = http.DefaultServeMux
+	}
+	for i := len(c.mws) - 1; i >= 0; i-- {
+		h = c.mws[i](h)
+	}
+
+	return h
+}
+
+// ThenFunc wraps f with the middlewares of the chain
+func (c Chain) ThenFunc(f http.HandlerFunc) http.Handler {
+	if f == nil {
+		return c.Then(nil)
+	}
+
+	return c.Then(f)
+}
+
+// OrderRule requires the middleware named Before to run before, that is
+// outside of, the middleware named After when the chain has both.
+// Middlewares are named after the function that created them,
+// such as "RequestID" or "Logger"
+type OrderRule struct {
+	Before string
+	After  string
+}
+
+// DefaultOrderRules are the orderings required by the middlewares
+// of the package to work together
+var DefaultOrderRules = []OrderRule{
+	// The loggers need the request id and the client ip
+	{Before: "RequestID", After: "Logger"},
+	{Before: "RequestID", After: "LoggerZero"},
+	{Before: "RealIP", After: "Logger"},
+	{Before: "RealIP", After: "LoggerZero"},
+	{Before: "RequestID", After: "LoggerSlog"},
+	{Before: "RealIP", After: "LoggerSlog"},
+	{Before: "RequestID", After: "AccessLog"},
+	{Before: "RealIP", After: "AccessLog"},
+	// Panics are logged only when recovered inside the loggers
+	{Before: "Logger", After: "Recover"},
+	{Before: "LoggerZero", After: "Recover"},
+	{Before: "LoggerSlog", After: "Recover"},
+	{Before: "AccessLog", After: "Recover"},
+	{Before: "RealIP", After: "Tracing"},
+	{Before: "RealIP", After: "IPFilter"},
+	{Before: "RealIP", After: "RateLimit"},
+	{Before: "RealIP", After: "RBAC"},
+	// Preflight requests don't carry credentials
+	{Before: "CORS", After: "JWTAuth"},
+	{Before: "CORS", After: "APIKeyAuth"},
+	{Before: "CORS", After: "BasicAuth"},
+	// Authorization needs the principal
+	{Before: "JWTAuth", After: "RBAC"},
+	{Before: "APIKeyAuth", After: "RBAC"},
+	{Before: "BasicAuth", After: "RBAC"},
+	{Before: "JWTAuth", After: "RequireMethodScopes"},
+	{Before: "APIKeyAuth", After: "RequireMethodScopes"},
+	{Before: "BasicAuth", After: "RequireMethodScopes"},
+}
+
+// Validate checks the order of the middlewares against the rules,
+// DefaultOrderRules when none are given, and reports all the violations.
+// Validation is opt-in: NewChain, Append, Extend and Then never validate,
+// NewCheckedChain, MustChain, the presets and Build do
+func (c Chain) Validate(rules ...OrderRule) error {
+	if len(rules) == 0 {
+		rules = DefaultOrderRules
+	}
+
+	positions := make(map[string][]int)
+	for i, mw := range c.mws {
+		name := middlewareName(mw)
+		positions[name] = append(positions[name], i)
+	}
+
+	var errs []error
+	for _, rule := range rules {
+		before, after := positions[rule.Before], positions[rule.After]
+		if len(before) == 0 || len(after) == 0 {
+			continue
+		}
+		if before[0] > after[0] {
+			errs = append(errs, fmt.Errorf(
+				"chain: %s (#%d) must come before %s (#%d)",
+				rule.Before, before[0], rule.After, after[0],
+			))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// middlewareName is the name of the function that created mw,
+// from the name of the closure it returned
+func middlewareName(mw Middleware) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(mw).Pointer())
+	if fn == nil {
+		return ""
+	}
+
+	return closureCreator(fn.Name())
+}
+
+// closureCreator is the function that created the named closure, such as
+// RequestID for gohttpmw.RequestID.func1. Before go 1.23 the closures of
+// inlined functions were named after their callers as well, such as
+// gohttpmw.APIStack.RequestID.func2, so the last function is kept
+func closureCreator(name string) string {
+	// Drop the package path, then the package name
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		name = name[i+1:]
+	}
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		name = name[i+1:]
+	}
+	// Drop the closure suffixes, .func1, .gowrap1 or .1 when nested
+	parts := strings.Split(name, ".")
+	for len(parts) > 1 && isClosureSuffix(parts[len(parts)-1]) {
+		parts = parts[:len(parts)-1]
+	}
+
+	return parts[len(parts)-1]
+}
+
+func isClosureSuffix(s string) bool {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "func"), "gowrap")
+
+	return s != "" && strings.Trim(s, "0123456789") == ""
+}
@@ -0,0 +1,212 @@
+package gohttpmw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func tagMiddleware(tag string) Middleware {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(tag))
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestChain(t *testing.T) {
+	base := NewChain(tagMiddleware("a"), tagMiddleware("b"))
+	appended := base.Append(tagMiddleware("c"))
+	extended := base.Extend(NewChain(tagMiddleware("d"), tagMiddleware("e")))
+	fakeHandler := func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte("h"))
+	}
+
+	tests := []struct {
+		name     string
+		handler  http.Handler
+		expected string
+	}{
+		{
+			name:     "empty chain",
+			handler:  NewChain().ThenFunc(fakeHandler),
+			expected: "h",
+		},
+		{
+			name:     "base",
+			handler:  base.ThenFunc(fakeHandler),
+			expected: "abh",
+		},
+		{
+			name:     "append doesn't change base",
+			handler:  appended.ThenFunc(fakeHandler),
+			expected: "abch",
+		},
+		{
+			name:     "extend",
+			handler:  extended.ThenFunc(fakeHandler),
+			expected: "abdeh",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, `/`, nil))
+			if rr.Body.String() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestChainAppendDoesNotShare(t *testing.T) {
+	base := NewChain(tagMiddleware("a")).Append(tagMiddleware("b"))
+	first := base.Append(tagMiddleware("1"))
+	_ = base.Append(tagMiddleware("2"))
+
+	rr := httptest.NewRecorder()
+	first.Then(http.NotFoundHandler()).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, `/`, nil))
+	if !strings.HasPrefix(rr.Body.String(), "ab1") {
+		t.Errorf("expected ab1, got %q", rr.Body.String())
+	}
+}
+
+func TestMiddlewareName(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	tests := []struct {
+		mw       Middleware
+		expected string
+	}{
+		{mw: RequestID(), expected: "RequestID"},
+		{mw: Logger(logger), expected: "Logger"},
+		{mw: Security(), expected: "Security"},
+		{mw: RBAC(nil, nil), expected: "RBAC"},
+		{mw: JWTAuth(nil), expected: "JWTAuth"},
+		{mw: RequireScopes("read"), expected: "RequireMethodScopes"},
+		{mw: RealIP(nil), expected: "RealIP"},
+		{mw: tagMiddleware("a"), expected: "tagMiddleware"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			if got := middlewareName(tt.mw); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestClosureCreator(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{name: "github.com/vincentserpoul/gohttpmw.RequestID.func1", expected: "RequestID"},
+		{name: "github.com/vincentserpoul/gohttpmw.RBAC.gowrap1", expected: "RBAC"},
+		{name: "github.com/vincentserpoul/gohttpmw.Logger.func1.1", expected: "Logger"},
+		// Closures of inlined functions before go 1.23
+		{name: "github.com/vincentserpoul/gohttpmw.APIStack.RequestID.func2", expected: "RequestID"},
+		{name: "main.main.func1", expected: "main"},
+		{name: "gohttpmw.Security", expected: "Security"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := closureCreator(tt.name); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestChainValidate(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+
+	tests := []struct {
+		name           string
+		chain          Chain
+		rules          []OrderRule
+		expectedErrors []string
+	}{
+		{
+			name:  "well ordered",
+			chain: NewChain(RequestID(), RealIP(nil), Logger(logger), JWTAuth(nil), RBAC(nil, nil)),
+		},
+		{
+			name:  "logger inside request id",
+			chain: NewChain(Logger(logger), RequestID(), Security()),
+			expectedErrors: []string{
+				"chain: RequestID (#1) must come before Logger (#0)",
+			},
+		},
+		{
+			name:  "rbac before auth and logger before request id",
+			chain: NewChain(Logger(logger), RequestID(), RBAC(nil, nil), JWTAuth(nil)),
+			expectedErrors: []string{
+				"chain: RequestID (#1) must come before Logger (#0)",
+				"chain: JWTAuth (#3) must come before RBAC (#2)",
+			},
+		},
+		{
+			name:  "custom rule",
+			chain: NewChain(Security(), tagMiddleware("a")),
+			rules: []OrderRule{{Before: "tagMiddleware", After: "Security"}},
+			expectedErrors: []string{
+				"chain: tagMiddleware (#1) must come before Security (#0)",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.chain.Validate(tt.rules...)
+			if len(tt.expectedErrors) == 0 {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected errors %v, got none", tt.expectedErrors)
+			}
+			if got := strings.Split(err.Error(), "\n"); strings.Join(got, "|") !=
+				strings.Join(tt.expectedErrors, "|") {
+				t.Errorf("expected errors %v, got %v", tt.expectedErrors, got)
+			}
+		})
+	}
+}
+
+func TestNewCheckedChain(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+
+	tests := []struct {
+		name        string
+		mws         []Middleware
+		expectedErr bool
+	}{
+		{name: "well ordered", mws: []Middleware{RequestID(), Logger(logger)}},
+		{name: "logger inside request id", mws: []Middleware{Logger(logger), RequestID()}, expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewCheckedChain(tt.mws...)
+			if (err != nil) != tt.expectedErr {
+				t.Errorf("expected error %t, got %v", tt.expectedErr, err)
+			}
+
+			defer func() {
+				if r := recover(); (r != nil) != tt.expectedErr {
+					t.Errorf("expected MustChain panic %t, got %v", tt.expectedErr, r)
+				}
+			}()
+			_ = MustChain(tt.mws...)
+		})
+	}
+}
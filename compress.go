@@ -0,0 +1,348 @@
+package gohttpmw
+
+import (
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Content encodings supported by Compress and Decompress
+const (
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+	EncodingBrotli  = "br"
+	EncodingZstd    = "zstd"
+)
+
+// ContextKeyCompression stores the compression stats of the response
+const ContextKeyCompression = ContextKey("compression")
+
+// CompressOption configures the Compress middleware
+type CompressOption func(*compressConfig)
+
+type compressConfig struct {
+	minSize   int
+	types     []string
+	encodings []string
+}
+
+// WithMinSize sets the size under which responses are not compressed,
+// 1024 bytes by default
+func WithMinSize(n int) CompressOption {
+	return func(c *compressConfig) {
+		c.minSize = n
+	}
+}
+
+// WithCompressibleTypes replaces the media types of the responses
+// to compress, "type/*" matches all the subtypes
+func WithCompressibleTypes(mediaTypes ...string) CompressOption {
+	return func(c *compressConfig) {
+		c.types = nil
+		for _, mt := range mediaTypes {
+			c.types = append(c.types, strings.ToLower(mt))
+		}
+	}
+}
+
+// WithEncodings sets the supported encodings in order of preference,
+// br, zstd, gzip and deflate by default
+func WithEncodings(encodings ...string) CompressOption {
+	return func(c *compressConfig) {
+		c.encodings = encodings
+	}
+}
+
+var defaultCompressibleTypes = []string{
+	"text/*",
+	"application/json",
+	"application/ld+json",
+	"application/problem+json",
+	"application/javascript",
+	"application/x-javascript",
+	"application/xml",
+	"application/wasm",
+	"image/svg+xml",
+}
+
+// compressEncoder is implemented by the encoders of all the encodings
+type compressEncoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(io.Writer)
+}
+
+var compressEncoderPools = map[string]*sync.Pool{
+	EncodingGzip: {New: func() interface{} {
+		return gzip.NewWriter(nil)
+	}},
+	EncodingDeflate: {New: func() interface{} {
+		return zlib.NewWriter(nil)
+	}},
+	EncodingBrotli: {New: func() interface{} {
+		return brotli.NewWriterLevel(nil, brotli.DefaultCompression)
+	}},
+	EncodingZstd: {New: func() interface{} {
+		// Options are constant so it can't fail
+		enc, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		return enc
+	}},
+}
+
+// compressionStats are shared through the context with the loggers
+type compressionStats struct {
+	encoding     string
+	uncompressed int
+}
+
+// Compress compresses the responses according to the Accept-Encoding of the
+// request. Responses smaller than the min size, of a type that is not
+// compressible, already encoded or answering a range request are sent
+// as is. When compressed, the resp_length of the loggers is the compressed
+// length and resp_uncompressed_length is added
+func Compress(opts ...CompressOption) func(http.Handler) http.Handler {
+	cfg := compressConfig{
+		minSize: 1024,
+		types:   defaultCompressibleTypes,
+		encodings: []string{
+			EncodingBrotli,
+			EncodingZstd,
+			EncodingGzip,
+			EncodingDeflate,
+		},
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			if r.Method == http.MethodHead || r.Header.Get("Range") != "" {
+				h.ServeHTTP(w, r)
+				return
+			}
+			encoding := negotiateEncoding(
+				r.Header.Get("Accept-Encoding"),
+				cfg.encodings,
+			)
+			if encoding == "" {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			stats := &compressionStats{}
+			*r = *r.WithContext(
+				context.WithValue(r.Context(), ContextKeyCompression, stats),
+			)
+
+			cw := &compressWriter{
+				ResponseWriter: w,
+				cfg:            &cfg,
+				encoding:       encoding,
+				stats:          stats,
+				status:         http.StatusOK,
+			}
+			defer cw.close()
+
+			h.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// getCompressionStats tells the encoding and the uncompressed length
+// of the response, if it was compressed
+func getCompressionStats(ctx context.Context) (string, int, bool) {
+	stats, ok := ctx.Value(ContextKeyCompression).(*compressionStats)
+	if !ok || stats.encoding == "" {
+		return "", 0, false
+	}
+
+	return stats.encoding, stats.uncompressed, true
+}
+
+// negotiateEncoding picks the encoding with the highest q value,
+// ties are broken by the order of preference of supported
+func negotiateEncoding(acceptEncoding string, supported []string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+
+	qs := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		qs[strings.ToLower(strings.TrimSpace(name))] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, enc := range supported {
+		q, ok := qs[enc]
+		if !ok {
+			q = qs["*"]
+		}
+		if q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+
+	return best
+}
+
+// compressWriter buffers the beginning of the response until it knows
+// if it is worth compressing
+type compressWriter struct {
+	http.ResponseWriter
+	cfg      *compressConfig
+	encoding string
+	stats    *compressionStats
+
+	status  int
+	buf     []byte
+	decided bool
+	enc     compressEncoder
+}
+
+func (cw *compressWriter) WriteHeader(httpStatus int) {
+	if cw.decided {
+		return
+	}
+	cw.status = httpStatus
+	if !cw.eligible() {
+		cw.decide(false)
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	cw.stats.uncompressed += len(b)
+
+	if !cw.decided {
+		if !cw.eligible() {
+			cw.decide(false)
+		} else {
+			cw.buf = append(cw.buf, b...)
+			if len(cw.buf) < cw.cfg.minSize {
+				return len(b), nil
+			}
+			if err := cw.decide(true); err != nil {
+				return 0, err
+			}
+			return len(b), nil
+		}
+	}
+
+	if cw.enc != nil {
+		return cw.enc.Write(b)
+	}
+
+	return cw.ResponseWriter.Write(b)
+}
+
+// Flush sends what was written so far, compressed if eligible
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		_ = cw.decide(len(cw.buf) > 0 && cw.eligible())
+	}
+	if cw.enc != nil {
+		_ = cw.enc.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// eligible tells if the response can be compressed from what is known of it
+func (cw *compressWriter) eligible() bool {
+	switch {
+	case cw.status < http.StatusOK,
+		cw.status == http.StatusNoContent,
+		cw.status == http.StatusPartialContent,
+		cw.status == http.StatusNotModified:
+		return false
+	}
+
+	h := cw.Header()
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+	if cl := h.Get("Content-Length"); cl != "" {
+		if n, err := strconv.Atoi(cl); err == nil && n < cw.cfg.minSize {
+			return false
+		}
+	}
+	if ct := h.Get("Content-Type"); ct != "" {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err != nil || !matchMediaType(cw.cfg.types, mediaType) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// decide writes the header, compressed or not, and the buffered bytes
+func (cw *compressWriter) decide(compress bool) error {
+	cw.decided = true
+
+	h := cw.Header()
+	if compress && h.Get("Content-Type") == "" {
+		h.Set("Content-Type", http.DetectContentType(cw.buf))
+		compress = cw.eligible()
+	}
+	if compress {
+		h.Del("Content-Length")
+		h.Del("Accept-Ranges")
+		h.Set("Content-Encoding", cw.encoding)
+		cw.enc = compressEncoderPools[cw.encoding].Get().(compressEncoder)
+		cw.enc.Reset(cw.ResponseWriter)
+		cw.stats.encoding = cw.encoding
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	if len(cw.buf) == 0 {
+		return nil
+	}
+	var err error
+	if cw.enc != nil {
+		_, err = cw.enc.Write(cw.buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(cw.buf)
+	}
+	cw.buf = nil
+
+	return err
+}
+
+// close sends what is left of the response and returns the encoder to its pool
+func (cw *compressWriter) close() {
+	if !cw.decided {
+		if len(cw.buf) == 0 && cw.status == http.StatusOK {
+			// Nothing was written, let the server write the default header
+			return
+		}
+		_ = cw.decide(false)
+	}
+	if cw.enc != nil {
+		_ = cw.enc.Close()
+		cw.enc.Reset(nil)
+		compressEncoderPools[cw.encoding].Put(cw.enc)
+		cw.enc = nil
+	}
+}
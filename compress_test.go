@@ -0,0 +1,267 @@
+package gohttpmw
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	supported := []string{EncodingBrotli, EncodingZstd, EncodingGzip, EncodingDeflate}
+	tests := []struct {
+		acceptEncoding string
+		expected       string
+	}{
+		{acceptEncoding: "", expected: ""},
+		{acceptEncoding: "gzip", expected: EncodingGzip},
+		{acceptEncoding: "gzip, deflate, br", expected: EncodingBrotli},
+		{acceptEncoding: "gzip;q=1.0, br;q=0.5", expected: EncodingGzip},
+		{acceptEncoding: "br;q=0, *", expected: EncodingZstd},
+		{acceptEncoding: "identity", expected: ""},
+		{acceptEncoding: "*;q=0", expected: ""},
+		{acceptEncoding: "GZIP", expected: EncodingGzip},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.acceptEncoding, func(t *testing.T) {
+			if got := negotiateEncoding(tt.acceptEncoding, supported); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat("compress me please ", 100)
+
+	tests := []struct {
+		name             string
+		acceptEncoding   string
+		rangeHeader      string
+		handler          http.HandlerFunc
+		expectedEncoding string
+		expectedBody     string
+		expectedStatus   int
+	}{
+		{
+			name:           "small response",
+			acceptEncoding: "gzip",
+			handler: http.HandlerFunc(
+				func(w http.ResponseWriter, req *http.Request) {
+					w.Header().Set("Content-Type", "text/plain")
+					_, _ = w.Write([]byte("small"))
+				}),
+			expectedBody:   "small",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "gzip",
+			acceptEncoding: "gzip",
+			handler: http.HandlerFunc(
+				func(w http.ResponseWriter, req *http.Request) {
+					w.Header().Set("Content-Type", "text/plain")
+					w.Header().Set("Content-Length", "1900")
+					_, _ = w.Write([]byte(large))
+				}),
+			expectedEncoding: EncodingGzip,
+			expectedBody:     large,
+			expectedStatus:   http.StatusOK,
+		},
+		{
+			name:           "deflate in several writes",
+			acceptEncoding: "deflate",
+			handler: http.HandlerFunc(
+				func(w http.ResponseWriter, req *http.Request) {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusCreated)
+					for i := 0; i < 100; i++ {
+						_, _ = w.Write([]byte("compress me please "))
+					}
+				}),
+			expectedEncoding: EncodingDeflate,
+			expectedBody:     large,
+			expectedStatus:   http.StatusCreated,
+		},
+		{
+			name:           "brotli with sniffed content type",
+			acceptEncoding: "br",
+			handler: http.HandlerFunc(
+				func(w http.ResponseWriter, req *http.Request) {
+					_, _ = w.Write([]byte(large))
+				}),
+			expectedEncoding: EncodingBrotli,
+			expectedBody:     large,
+			expectedStatus:   http.StatusOK,
+		},
+		{
+			name:           "zstd",
+			acceptEncoding: "zstd",
+			handler: http.HandlerFunc(
+				func(w http.ResponseWriter, req *http.Request) {
+					w.Header().Set("Content-Type", "text/html; charset=utf-8")
+					_, _ = w.Write([]byte(large))
+				}),
+			expectedEncoding: EncodingZstd,
+			expectedBody:     large,
+			expectedStatus:   http.StatusOK,
+		},
+		{
+			name:           "not compressible",
+			acceptEncoding: "gzip",
+			handler: http.HandlerFunc(
+				func(w http.ResponseWriter, req *http.Request) {
+					w.Header().Set("Content-Type", "image/png")
+					_, _ = w.Write([]byte(large))
+				}),
+			expectedBody:   large,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "already encoded",
+			acceptEncoding: "gzip",
+			handler: http.HandlerFunc(
+				func(w http.ResponseWriter, req *http.Request) {
+					w.Header().Set("Content-Type", "text/plain")
+					w.Header().Set("Content-Encoding", "custom")
+					_, _ = w.Write([]byte(large))
+				}),
+			expectedEncoding: "custom",
+			expectedBody:     large,
+			expectedStatus:   http.StatusOK,
+		},
+		{
+			name:           "range request",
+			acceptEncoding: "gzip",
+			rangeHeader:    "bytes=0-10",
+			handler: http.HandlerFunc(
+				func(w http.ResponseWriter, req *http.Request) {
+					w.Header().Set("Content-Type", "text/plain")
+					_, _ = w.Write([]byte(large))
+				}),
+			expectedBody:   large,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "empty response",
+			acceptEncoding: "gzip",
+			handler: http.HandlerFunc(
+				func(w http.ResponseWriter, req *http.Request) {
+					w.WriteHeader(http.StatusNotFound)
+				}),
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name: "no accept encoding",
+			handler: http.HandlerFunc(
+				func(w http.ResponseWriter, req *http.Request) {
+					_, _ = w.Write([]byte(large))
+				}),
+			expectedBody:   large,
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			midWared := Compress()(tt.handler)
+			rr := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, `/`, nil)
+			if tt.acceptEncoding != "" {
+				request.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			if tt.rangeHeader != "" {
+				request.Header.Set("Range", tt.rangeHeader)
+			}
+			midWared.ServeHTTP(rr, request)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if rr.Header().Get("Vary") != "Accept-Encoding" {
+				t.Errorf("expected Vary: Accept-Encoding, got %q", rr.Header().Get("Vary"))
+			}
+			encoding := rr.Header().Get("Content-Encoding")
+			if encoding != tt.expectedEncoding {
+				t.Errorf("expected encoding %q, got %q", tt.expectedEncoding, encoding)
+			}
+			if encoding != "" && encoding != "custom" &&
+				rr.Header().Get("Content-Length") != "" {
+				t.Errorf("expected no Content-Length on compressed response")
+			}
+
+			body := decodeTestBody(t, encoding, rr.Body.Bytes())
+			if body != tt.expectedBody {
+				t.Errorf("expected body of %d bytes, got %d", len(tt.expectedBody), len(body))
+			}
+		})
+	}
+}
+
+func TestCompressLogger(t *testing.T) {
+	large := strings.Repeat("compress me please ", 100)
+	fakeHandler := http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			_, _ = w.Write([]byte(large))
+		},
+	)
+	logger, hook := test.NewNullLogger()
+	midWared := Logger(logger)(Compress()(fakeHandler))
+
+	rr := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, `/`, nil)
+	request.Header.Set("Accept-Encoding", "gzip")
+	midWared.ServeHTTP(rr, request)
+
+	data := hook.LastEntry().Data
+	if data["resp_length"] != rr.Body.Len() {
+		t.Errorf("expected resp_length %d, got %v", rr.Body.Len(), data["resp_length"])
+	}
+	if data["resp_uncompressed_length"] != len(large) {
+		t.Errorf(
+			"expected resp_uncompressed_length %d, got %v",
+			len(large), data["resp_uncompressed_length"],
+		)
+	}
+	if data["resp_encoding"] != EncodingGzip {
+		t.Errorf("expected resp_encoding gzip, got %v", data["resp_encoding"])
+	}
+}
+
+func decodeTestBody(t *testing.T, encoding string, b []byte) string {
+	t.Helper()
+
+	var (
+		r   io.Reader
+		err error
+	)
+	switch encoding {
+	case EncodingGzip:
+		r, err = gzip.NewReader(bytes.NewReader(b))
+	case EncodingDeflate:
+		r, err = zlib.NewReader(bytes.NewReader(b))
+	case EncodingBrotli:
+		r = brotli.NewReader(bytes.NewReader(b))
+	case EncodingZstd:
+		r, err = zstd.NewReader(bytes.NewReader(b))
+	default:
+		return string(b)
+	}
+	if err != nil {
+		t.Fatalf("could not decode %s body: %v", encoding, err)
+	}
+	decoded, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("could not decode %s body: %v", encoding, err)
+	}
+
+	return string(decoded)
+}
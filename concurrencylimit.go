@@ -0,0 +1,199 @@
+package gohttpmw
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrLoadShed is recorded as the request error of the requests
+// shed by MaxInFlight
+var ErrLoadShed = errors.New("too many requests in flight, load shed")
+
+// ConcurrencyLimiter limits the number of requests handled at the same time,
+// the limit is adjusted by its LimitAlgorithm from the observed latencies
+type ConcurrencyLimiter struct {
+	alg          LimitAlgorithm
+	maxQueue     int
+	queueTimeout time.Duration
+	retryAfter   time.Duration
+	exempt       func(*http.Request) bool
+
+	mu       sync.Mutex
+	limit    float64
+	inFlight int
+	// queue holds the chan struct{} of the waiting requests,
+	// closed when they are granted a slot
+	queue *list.List
+}
+
+// ConcurrencyOption configures a ConcurrencyLimiter
+type ConcurrencyOption func(*ConcurrencyLimiter)
+
+// WithQueue lets up to size requests wait for at most timeout
+// for a slot before being shed, requests are shed right away by default
+func WithQueue(size int, timeout time.Duration) ConcurrencyOption {
+	return func(l *ConcurrencyLimiter) {
+		l.maxQueue = size
+		l.queueTimeout = timeout
+	}
+}
+
+// WithExempt lets the requests matching exempt, such as health checks,
+// bypass the limiter
+func WithExempt(exempt func(*http.Request) bool) ConcurrencyOption {
+	return func(l *ConcurrencyLimiter) {
+		l.exempt = exempt
+	}
+}
+
+// WithRetryAfter sets the Retry-After of the shed requests, 1s by default
+func WithRetryAfter(d time.Duration) ConcurrencyOption {
+	return func(l *ConcurrencyLimiter) {
+		l.retryAfter = d
+	}
+}
+
+// NewConcurrencyLimiter creates a limiter starting at initial requests in
+// flight, if alg is nil the limit stays fixed
+func NewConcurrencyLimiter(
+	initial int,
+	alg LimitAlgorithm,
+	opts ...ConcurrencyOption,
+) *ConcurrencyLimiter {
+	if alg == nil {
+		alg = FixedLimit{}
+	}
+	l := &ConcurrencyLimiter{
+		alg:        alg,
+		retryAfter: time.Second,
+		limit:      float64(initial),
+		queue:      list.New(),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+// Limit returns the current limit of requests in flight
+func (l *ConcurrencyLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return int(l.limit)
+}
+
+// InFlight returns the number of requests being handled
+func (l *ConcurrencyLimiter) InFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.inFlight
+}
+
+// QueueDepth returns the number of requests waiting for a slot
+func (l *ConcurrencyLimiter) QueueDepth() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.queue.Len()
+}
+
+// MaxInFlight limits the requests handled at the same time with l,
+// the requests that can't get a slot get a 503 with a Retry-After header
+// and are recorded as request errors
+func MaxInFlight(l *ConcurrencyLimiter) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if l.exempt != nil && l.exempt(r) {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			if !l.acquire(r.Context()) {
+				SetRequestError(r, ErrLoadShed)
+				w.Header().Set("Retry-After", ceilSeconds(l.retryAfter))
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+
+			naw := newAugmentedResponseWriter(w)
+			startTime := time.Now()
+			defer func() {
+				l.release(time.Since(startTime), naw.httpStatus)
+			}()
+
+			h.ServeHTTP(naw, r)
+		})
+	}
+}
+
+func (l *ConcurrencyLimiter) acquire(ctx context.Context) bool {
+	l.mu.Lock()
+	if l.inFlight < l.intLimit() && l.queue.Len() == 0 {
+		l.inFlight++
+		l.mu.Unlock()
+		return true
+	}
+	if l.queue.Len() >= l.maxQueue {
+		l.mu.Unlock()
+		return false
+	}
+	granted := make(chan struct{})
+	elem := l.queue.PushBack(granted)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-granted:
+		return true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// The slot may have been granted while giving up
+	select {
+	case <-granted:
+		return true
+	default:
+	}
+	l.queue.Remove(elem)
+
+	return false
+}
+
+func (l *ConcurrencyLimiter) release(latency time.Duration, httpStatus int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Timeouts and unavailable dependencies are signs of overload
+	didDrop := httpStatus == http.StatusServiceUnavailable ||
+		httpStatus == http.StatusGatewayTimeout
+	l.limit = l.alg.Update(l.limit, latency, l.inFlight, didDrop)
+	l.inFlight--
+
+	// Hand over the free slots to the waiting requests
+	for l.inFlight < l.intLimit() && l.queue.Len() > 0 {
+		granted := l.queue.Remove(l.queue.Front()).(chan struct{})
+		l.inFlight++
+		close(granted)
+	}
+}
+
+// intLimit never goes below 1 so that requests are still served
+func (l *ConcurrencyLimiter) intLimit() int {
+	if l.limit < 1 {
+		return 1
+	}
+
+	return int(l.limit)
+}
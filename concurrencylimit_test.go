@@ -0,0 +1,135 @@
+package gohttpmw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMaxInFlight(t *testing.T) {
+	l := NewConcurrencyLimiter(
+		1, nil,
+		WithQueue(1, time.Second),
+		WithExempt(func(r *http.Request) bool { return r.URL.Path == "/health" }),
+		WithRetryAfter(2*time.Second),
+	)
+
+	started := make(chan struct{}, 3)
+	unblock := make(chan struct{})
+	fakeHandler := http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == "/health" {
+				return
+			}
+			started <- struct{}{}
+			<-unblock
+		},
+	)
+	midWared := MaxInFlight(l)(fakeHandler)
+
+	codes := make(chan int, 2)
+	var wg sync.WaitGroup
+	serve := func() {
+		defer wg.Done()
+		rr := httptest.NewRecorder()
+		midWared.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, `/`, nil))
+		codes <- rr.Code
+	}
+
+	// The first request takes the only slot, the second one queues
+	wg.Add(2)
+	go serve()
+	<-started
+	go serve()
+	waitFor(t, func() bool { return l.QueueDepth() == 1 })
+
+	if l.Limit() != 1 || l.InFlight() != 1 {
+		t.Errorf(
+			"expected limit 1 and 1 in flight, got %d and %d",
+			l.Limit(), l.InFlight(),
+		)
+	}
+
+	// The queue is full, the third request is shed
+	rr := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, `/`, nil)
+	midWared.ServeHTTP(rr, request)
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected request to be shed, got %d", rr.Code)
+	}
+	if got := rr.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("expected Retry-After 2, got %s", got)
+	}
+	if err := GetRequestError(request.Context()); err != ErrLoadShed {
+		t.Errorf("expected %v, got %v", ErrLoadShed, err)
+	}
+
+	// Health checks are never limited
+	rr = httptest.NewRecorder()
+	midWared.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, `/health`, nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected health check to be served, got %d", rr.Code)
+	}
+
+	close(unblock)
+	wg.Wait()
+	close(codes)
+	for code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("expected the queued request to be served, got %d", code)
+		}
+	}
+	if l.InFlight() != 0 || l.QueueDepth() != 0 {
+		t.Errorf("expected an idle limiter, got %d in flight, %d queued",
+			l.InFlight(), l.QueueDepth())
+	}
+}
+
+func TestMaxInFlightQueueTimeout(t *testing.T) {
+	l := NewConcurrencyLimiter(1, nil, WithQueue(1, 10*time.Millisecond))
+
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	fakeHandler := http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			close(started)
+			<-unblock
+		},
+	)
+	midWared := MaxInFlight(l)(fakeHandler)
+
+	done := make(chan struct{})
+	go func() {
+		midWared.ServeHTTP(
+			httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, `/`, nil),
+		)
+		close(done)
+	}()
+	<-started
+
+	rr := httptest.NewRecorder()
+	midWared.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, `/`, nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected the queued request to time out, got %d", rr.Code)
+	}
+	if l.QueueDepth() != 0 {
+		t.Errorf("expected the timed out request to leave the queue")
+	}
+
+	close(unblock)
+	<-done
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	for i := 0; i < 100; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("condition not met in time")
+}
@@ -0,0 +1,123 @@
+package gohttpmw
+
+import (
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// When applies mw only to the requests matching pred,
+// the other requests go straight to the handler
+func When(pred func(*http.Request) bool, mw Middleware) Middleware {
+	return func(h http.Handler) http.Handler {
+		wrapped := mw(h)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if pred(r) {
+				wrapped.ServeHTTP(w, r)
+				return
+			}
+
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Unless applies mw only to the requests not matching pred
+func Unless(pred func(*http.Request) bool, mw Middleware) Middleware {
+	return When(func(r *http.Request) bool { return !pred(r) }, mw)
+}
+
+// ForPathPrefix applies mw only to the requests under prefix
+func ForPathPrefix(prefix string, mw Middleware) Middleware {
+	return When(PathPrefix(prefix), mw)
+}
+
+// ForMethods applies mw only to the requests of one of methods
+func ForMethods(methods []string, mw Middleware) Middleware {
+	return When(Methods(methods...), mw)
+}
+
+// ForHost applies mw only to the requests for host,
+// see Host for the patterns accepted
+func ForHost(host string, mw Middleware) Middleware {
+	return When(Host(host), mw)
+}
+
+// PathPrefix matches the requests with a path under prefix on segment
+// boundaries, /admin matches /admin and /admin/users but not /administrator.
+// A trailing slash in prefix is ignored
+func PathPrefix(prefix string) func(*http.Request) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+
+	return func(r *http.Request) bool {
+		return r.URL.Path == prefix || strings.HasPrefix(r.URL.Path, prefix+"/")
+	}
+}
+
+// PathGlob matches the requests with a path matching pattern,
+// * matches within a path segment, ** across segments and ? one character,
+// such as /api/*/admin/** or /static/*.js
+func PathGlob(pattern string) func(*http.Request) bool {
+	return PathRegexp(globToRegexp(pattern))
+}
+
+// PathRegexp matches the requests with a path matching re
+func PathRegexp(re *regexp.Regexp) func(*http.Request) bool {
+	return func(r *http.Request) bool {
+		return re.MatchString(r.URL.Path)
+	}
+}
+
+// Methods matches the requests of one of methods
+func Methods(methods ...string) func(*http.Request) bool {
+	set := make(map[string]struct{}, len(methods))
+	for _, m := range methods {
+		set[strings.ToUpper(m)] = struct{}{}
+	}
+
+	return func(r *http.Request) bool {
+		_, ok := set[r.Method]
+		return ok
+	}
+}
+
+// Host matches the requests for host, ignoring the port and the case,
+// a leading *. matches any subdomain such as *.example.com.
+// The host is the one resolved by RealIP when used before
+func Host(host string) func(*http.Request) bool {
+	host = strings.ToLower(host)
+
+	return func(r *http.Request) bool {
+		reqHost := strings.ToLower(requestHost(r))
+		if h, _, err := net.SplitHostPort(reqHost); err == nil {
+			reqHost = h
+		}
+		if suffix, ok := strings.CutPrefix(host, "*."); ok {
+			return strings.HasSuffix(reqHost, "."+suffix)
+		}
+
+		return reqHost == host
+	}
+}
+
+func globToRegexp(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '*' && i+1 < len(pattern) && pattern[i+1] == '*':
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+
+	return regexp.MustCompile(b.String())
+}
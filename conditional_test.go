@@ -0,0 +1,158 @@
+package gohttpmw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestConditional(t *testing.T) {
+	fakeHandler := http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {},
+	)
+
+	tests := []struct {
+		name     string
+		mw       Middleware
+		method   string
+		target   string
+		expected bool
+	}{
+		{
+			name:     "when matching",
+			mw:       When(func(r *http.Request) bool { return true }, tagMiddleware("x")),
+			method:   http.MethodGet,
+			target:   "/",
+			expected: true,
+		},
+		{
+			name:     "unless matching",
+			mw:       Unless(func(r *http.Request) bool { return true }, tagMiddleware("x")),
+			method:   http.MethodGet,
+			target:   "/",
+			expected: false,
+		},
+		{
+			name:     "path prefix",
+			mw:       ForPathPrefix("/admin", tagMiddleware("x")),
+			method:   http.MethodGet,
+			target:   "/admin/users",
+			expected: true,
+		},
+		{
+			name:     "other path prefix",
+			mw:       ForPathPrefix("/admin", tagMiddleware("x")),
+			method:   http.MethodGet,
+			target:   "/users",
+			expected: false,
+		},
+		{
+			name:     "methods",
+			mw:       ForMethods([]string{"post", http.MethodPut}, tagMiddleware("x")),
+			method:   http.MethodPost,
+			target:   "/",
+			expected: true,
+		},
+		{
+			name:     "other methods",
+			mw:       ForMethods([]string{http.MethodPost}, tagMiddleware("x")),
+			method:   http.MethodGet,
+			target:   "/",
+			expected: false,
+		},
+		{
+			name:     "host with port",
+			mw:       ForHost("API.example.com", tagMiddleware("x")),
+			method:   http.MethodGet,
+			target:   "http://api.example.com:8080/",
+			expected: true,
+		},
+		{
+			name:     "subdomain wildcard",
+			mw:       ForHost("*.example.com", tagMiddleware("x")),
+			method:   http.MethodGet,
+			target:   "http://eu.api.example.com/",
+			expected: true,
+		},
+		{
+			name:     "subdomain wildcard excludes the domain",
+			mw:       ForHost("*.example.com", tagMiddleware("x")),
+			method:   http.MethodGet,
+			target:   "http://example.com/",
+			expected: false,
+		},
+		{
+			name:     "path regexp",
+			mw:       When(PathRegexp(regexp.MustCompile(`^/users/\d+$`)), tagMiddleware("x")),
+			method:   http.MethodGet,
+			target:   "/users/42",
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			request := httptest.NewRequest(tt.method, tt.target, nil)
+			tt.mw(fakeHandler).ServeHTTP(rr, request)
+
+			if applied := rr.Body.String() == "x"; applied != tt.expected {
+				t.Errorf("expected applied %t, got %t", tt.expected, applied)
+			}
+		})
+	}
+}
+
+func TestPathPrefix(t *testing.T) {
+	tests := []struct {
+		prefix   string
+		path     string
+		expected bool
+	}{
+		{prefix: "/admin", path: "/admin", expected: true},
+		{prefix: "/admin", path: "/admin/", expected: true},
+		{prefix: "/admin", path: "/admin/users", expected: true},
+		{prefix: "/admin", path: "/administrator", expected: false},
+		{prefix: "/admin", path: "/admin-panel/users", expected: false},
+		{prefix: "/admin/", path: "/admin", expected: true},
+		{prefix: "/admin/", path: "/admin/users", expected: true},
+		{prefix: "/admin/", path: "/administrator", expected: false},
+		{prefix: "/", path: "/users", expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prefix+" "+tt.path, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if got := PathPrefix(tt.prefix)(request); got != tt.expected {
+				t.Errorf("expected %t, got %t", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestPathGlob(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		path     string
+		expected bool
+	}{
+		{pattern: "/static/*.js", path: "/static/app.js", expected: true},
+		{pattern: "/static/*.js", path: "/static/js/app.js", expected: false},
+		{pattern: "/static/*.js", path: "/static/app.jsx", expected: false},
+		{pattern: "/api/*/admin/**", path: "/api/v1/admin/users/1", expected: true},
+		{pattern: "/api/*/admin/**", path: "/api/v1/users", expected: false},
+		{pattern: "/v?/health", path: "/v2/health", expected: true},
+		{pattern: "/v?/health", path: "/v10/health", expected: false},
+		{pattern: "/a.b", path: "/axb", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if got := PathGlob(tt.pattern)(request); got != tt.expected {
+				t.Errorf("expected %t, got %t", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,461 @@
+package gohttpmw
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ory/ladon"
+	manager "github.com/ory/ladon/manager/memory"
+	"github.com/sirupsen/logrus"
+)
+
+// Config describes a middleware stack, the sections left empty
+// are not part of the stack. The env tags follow the env/envPrefix
+// convention of github.com/caarlos0/env
+type Config struct {
+	RequestID RequestIDConfig `yaml:"request_id" json:"request_id" envPrefix:"REQUEST_ID_"`
+	RealIP    RealIPConfig    `yaml:"real_ip" json:"real_ip" envPrefix:"REAL_IP_"`
+	Logging   LoggingConfig   `yaml:"logging" json:"logging" envPrefix:"LOGGING_"`
+	Security  SecurityConfig  `yaml:"security" json:"security" envPrefix:"SECURITY_"`
+	CSP       CSPConfig       `yaml:"csp" json:"csp" envPrefix:"CSP_"`
+	CORS      CORSConfig      `yaml:"cors" json:"cors" envPrefix:"CORS_"`
+	RateLimit RateLimitConfig `yaml:"rate_limit" json:"rate_limit" envPrefix:"RATE_LIMIT_"`
+	RBAC      RBACConfig      `yaml:"rbac" json:"rbac"`
+}
+
+// RequestIDConfig enables RequestID
+type RequestIDConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled" env:"ENABLED"`
+}
+
+// RealIPConfig enables RealIP when proxies are trusted, Header is
+// X-Forwarded-For, Forwarded or X-Real-IP, X-Forwarded-For by default
+type RealIPConfig struct {
+	TrustedProxies []string `yaml:"trusted_proxies" json:"trusted_proxies" env:"TRUSTED_PROXIES"`
+	Header         string   `yaml:"header" json:"header" env:"HEADER"`
+}
+
+// LoggingConfig enables Logger, Fields are added to every request log
+type LoggingConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled" env:"ENABLED"`
+	// Format is json, the default, or text
+	Format string `yaml:"format" json:"format" env:"FORMAT"`
+	// Level is info by default
+	Level  string            `yaml:"level" json:"level" env:"LEVEL"`
+	Fields map[string]string `yaml:"fields" json:"fields" env:"FIELDS"`
+}
+
+// SecurityConfig enables Security, Headers are added to its headers
+type SecurityConfig struct {
+	Enabled bool              `yaml:"enabled" json:"enabled" env:"ENABLED"`
+	Headers map[string]string `yaml:"headers" json:"headers" env:"HEADERS"`
+}
+
+// CSPConfig enables CSP, with the sources of each directive
+// separated by spaces, such as default-src: "'self' cdn.example.com"
+type CSPConfig struct {
+	Directives map[string]string `yaml:"directives" json:"directives" env:"DIRECTIVES"`
+}
+
+// CORSConfig enables CORS when origins are allowed
+type CORSConfig struct {
+	AllowedOrigins   []string `yaml:"allowed_origins" json:"allowed_origins" env:"ALLOWED_ORIGINS"`
+	AllowedMethods   []string `yaml:"allowed_methods" json:"allowed_methods" env:"ALLOWED_METHODS"`
+	AllowedHeaders   []string `yaml:"allowed_headers" json:"allowed_headers" env:"ALLOWED_HEADERS"`
+	ExposedHeaders   []string `yaml:"exposed_headers" json:"exposed_headers" env:"EXPOSED_HEADERS"`
+	AllowCredentials bool     `yaml:"allow_credentials" json:"allow_credentials" env:"ALLOW_CREDENTIALS"`
+	MaxAge           Duration `yaml:"max_age" json:"max_age" env:"MAX_AGE"`
+}
+
+// RateLimitConfig enables RateLimit when the limit is set
+type RateLimitConfig struct {
+	// Algorithm is token_bucket, the default, or sliding_window
+	Algorithm string   `yaml:"algorithm" json:"algorithm" env:"ALGORITHM"`
+	Limit     int      `yaml:"limit" json:"limit" env:"LIMIT"`
+	Period    Duration `yaml:"period" json:"period" env:"PERIOD"`
+	// Key is ip, the default, principal or apikey, the last two
+	// need an authentication passed to Build with WithAuth
+	Key string `yaml:"key" json:"key" env:"KEY"`
+}
+
+// RBACConfig enables RBAC with in-memory policies, the roles
+// of the principal are the subjects so Build needs WithAuth
+type RBACConfig struct {
+	Policies []RBACPolicyConfig `yaml:"policies" json:"policies"`
+	// Resources map the requests to the resources of the policies,
+	// the first match wins and the request uri is used otherwise
+	Resources []RBACResourceConfig `yaml:"resources" json:"resources"`
+}
+
+// RBACPolicyConfig is a ladon policy
+type RBACPolicyConfig struct {
+	ID          string   `yaml:"id" json:"id"`
+	Description string   `yaml:"description" json:"description"`
+	Subjects    []string `yaml:"subjects" json:"subjects"`
+	Resources   []string `yaml:"resources" json:"resources"`
+	Actions     []string `yaml:"actions" json:"actions"`
+	// Effect is allow or deny
+	Effect string `yaml:"effect" json:"effect"`
+}
+
+// RBACResourceConfig maps the requests matching the Path glob, see PathGlob,
+// and one of Methods if any, to Resource
+type RBACResourceConfig struct {
+	Path     string   `yaml:"path" json:"path"`
+	Methods  []string `yaml:"methods" json:"methods"`
+	Resource string   `yaml:"resource" json:"resource"`
+}
+
+// Duration is a time.Duration read from strings such as "1m30s"
+type Duration time.Duration
+
+// UnmarshalText parses the duration, it is also used for json and env
+func (d *Duration) UnmarshalText(b []byte) error {
+	parsed, err := time.ParseDuration(string(b))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+
+	return nil
+}
+
+// MarshalText formats the duration
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalYAML parses the duration for gopkg.in/yaml
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+
+	return d.UnmarshalText([]byte(s))
+}
+
+var (
+	cspDirectiveName = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+	headerName       = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+)
+
+// BuildOption adds to the stack assembled by Build
+// what can't be described in a Config
+type BuildOption func(*buildConfig)
+
+type buildConfig struct {
+	auth []Middleware
+}
+
+// WithAuth adds the authentication middlewares, such as JWTAuth or
+// BasicAuth, after CORS and before RBAC and the RateLimit keyed by
+// principal or api key, which need the principal they set. The RateLimit
+// keyed by ip comes before them so that it throttles the failed attempts
+func WithAuth(mws ...Middleware) BuildOption {
+	return func(c *buildConfig) {
+		c.auth = append(c.auth, mws...)
+	}
+}
+
+// Build validates cfg and assembles its middlewares in the order of
+// DefaultOrderRules, all the errors of the configuration are reported
+func Build(cfg Config, opts ...BuildOption) (func(http.Handler) http.Handler, error) {
+	var bc buildConfig
+	for _, opt := range opts {
+		opt(&bc)
+	}
+
+	var (
+		c    = NewChain()
+		errs []error
+	)
+	fail := func(field, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("config: "+field+": "+format, args...))
+	}
+
+	if cfg.RequestID.Enabled {
+		c = c.Append(RequestID())
+	}
+
+	if len(cfg.RealIP.TrustedProxies) > 0 {
+		header := RealIPXForwardedFor
+		if cfg.RealIP.Header != "" {
+			h, err := ParseRealIPHeader(cfg.RealIP.Header)
+			if err != nil {
+				fail("real_ip.header", "%v", err)
+			}
+			header = h
+		}
+		tp, err := NewTrustedProxies(cfg.RealIP.TrustedProxies...)
+		if err != nil {
+			fail("real_ip.trusted_proxies", "%v", err)
+		} else {
+			c = c.Append(RealIP(tp, WithRealIPHeader(header)))
+		}
+	}
+
+	if cfg.Logging.Enabled {
+		l := logrus.New()
+		switch cfg.Logging.Format {
+		case "", "json":
+			l.SetFormatter(&logrus.JSONFormatter{})
+		case "text":
+			l.SetFormatter(&logrus.TextFormatter{})
+		default:
+			fail("logging.format", "unknown format %q, expected json or text", cfg.Logging.Format)
+		}
+		if cfg.Logging.Level != "" {
+			level, err := logrus.ParseLevel(cfg.Logging.Level)
+			if err != nil {
+				fail("logging.level", "%v", err)
+			} else {
+				l.SetLevel(level)
+			}
+		}
+		c = c.Append(Logger(l))
+		for _, k := range sortedKeys(cfg.Logging.Fields) {
+			v := cfg.Logging.Fields[k]
+			c = c.Append(AddToRequestLog(k, func(context.Context) interface{} { return v }))
+		}
+		c = c.Append(Recover())
+	}
+
+	if cfg.Security.Enabled {
+		c = c.Append(Security())
+	}
+	if len(cfg.Security.Headers) > 0 {
+		for _, k := range sortedKeys(cfg.Security.Headers) {
+			if !headerName.MatchString(k) {
+				fail("security.headers", "invalid header name %q", k)
+			}
+		}
+		c = c.Append(staticHeaders(cfg.Security.Headers))
+	}
+
+	if len(cfg.CSP.Directives) > 0 {
+		directives := make([]string, 0, len(cfg.CSP.Directives))
+		for _, name := range sortedKeys(cfg.CSP.Directives) {
+			sources := strings.TrimSpace(cfg.CSP.Directives[name])
+			if !cspDirectiveName.MatchString(name) {
+				fail("csp.directives", "invalid directive name %q", name)
+			}
+			if strings.ContainsAny(sources, ";,") {
+				fail("csp.directives."+name, "sources can't contain ; or ,")
+			}
+			directives = append(directives, strings.TrimSpace(name+" "+sources))
+		}
+		c = c.Append(CSP(strings.Join(directives, "; ")))
+	}
+
+	if len(cfg.CORS.AllowedOrigins) > 0 {
+		for i, origin := range cfg.CORS.AllowedOrigins {
+			if err := validateCORSOrigin(origin); err != nil {
+				fail(fmt.Sprintf("cors.allowed_origins[%d]", i), "%v", err)
+			}
+			if origin == "*" && cfg.CORS.AllowCredentials {
+				fail("cors.allow_credentials", "credentials can't be allowed for all origins")
+			}
+		}
+		c = c.Append(CORS(CORSOptions{
+			AllowedOrigins:   cfg.CORS.AllowedOrigins,
+			AllowedMethods:   cfg.CORS.AllowedMethods,
+			AllowedHeaders:   cfg.CORS.AllowedHeaders,
+			ExposedHeaders:   cfg.CORS.ExposedHeaders,
+			AllowCredentials: cfg.CORS.AllowCredentials,
+			MaxAge:           time.Duration(cfg.CORS.MaxAge),
+		}))
+	}
+
+	// authenticated is the part of the chain after the authentication
+	authenticated := NewChain(bc.auth...)
+
+	if rl := cfg.RateLimit; rl != (RateLimitConfig{}) {
+		if rl.Limit <= 0 {
+			fail("rate_limit.limit", "must be positive")
+		}
+		if rl.Period <= 0 {
+			fail("rate_limit.period", "must be positive")
+		}
+		var store RateLimitStore
+		switch rl.Algorithm {
+		case "", "token_bucket":
+			store = NewTokenBucketStore(rl.Limit, time.Duration(rl.Period))
+		case "sliding_window":
+			store = NewSlidingWindowStore(rl.Limit, time.Duration(rl.Period))
+		default:
+			fail(
+				"rate_limit.algorithm",
+				"unknown algorithm %q, expected token_bucket or sliding_window",
+				rl.Algorithm,
+			)
+		}
+		var key func(*http.Request) string
+		switch rl.Key {
+		case "", "ip":
+			key = KeyByRemoteIP
+		case "principal":
+			key = KeyByPrincipal
+		case "apikey":
+			key = KeyByAPIKey
+		default:
+			fail("rate_limit.key", "unknown key %q, expected ip, principal or apikey", rl.Key)
+		}
+		if (rl.Key == "principal" || rl.Key == "apikey") && len(bc.auth) == 0 {
+			fail("rate_limit.key", "%s needs an authentication, see WithAuth", rl.Key)
+		}
+		if store != nil && key != nil {
+			if rl.Key == "" || rl.Key == "ip" {
+				c = c.Append(RateLimit(store, key))
+			} else {
+				authenticated = authenticated.Append(RateLimit(store, key))
+			}
+		}
+	}
+
+	if len(cfg.RBAC.Policies) > 0 {
+		if len(bc.auth) == 0 {
+			// Every request would be checked without subject and denied
+			fail("rbac.policies", "needs an authentication, see WithAuth")
+		}
+		warden := &ladon.Ladon{Manager: manager.NewMemoryManager()}
+		for i, p := range cfg.RBAC.Policies {
+			if err := createRBACPolicy(warden.Manager, p); err != nil {
+				fail(fmt.Sprintf("rbac.policies[%d]", i), "%v", err)
+			}
+		}
+		var opts []RBACOption
+		if len(cfg.RBAC.Resources) > 0 {
+			resource, resErrs := rbacResourceMapping(cfg.RBAC.Resources)
+			errs = append(errs, resErrs...)
+			opts = append(opts, WithResource(resource))
+		}
+		authenticated = authenticated.Append(RBAC(warden, nil, opts...))
+	} else if len(cfg.RBAC.Resources) > 0 {
+		fail("rbac.resources", "no policies to map resources to")
+	}
+
+	c = c.Extend(authenticated)
+	if err := c.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return c.Then, nil
+}
+
+func staticHeaders(headers map[string]string) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for k, v := range headers {
+				w.Header().Set(k, v)
+			}
+
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+func validateCORSOrigin(origin string) error {
+	if origin == "*" {
+		return nil
+	}
+	u, err := url.Parse(strings.Replace(origin, "://*.", "://", 1))
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+		(u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+		return fmt.Errorf("invalid origin %q, expected scheme://host[:port]", origin)
+	}
+
+	return nil
+}
+
+func createRBACPolicy(m ladon.Manager, p RBACPolicyConfig) error {
+	var errs []error
+	if p.ID == "" {
+		errs = append(errs, errors.New("id is required"))
+	}
+	if len(p.Subjects) == 0 || len(p.Resources) == 0 || len(p.Actions) == 0 {
+		errs = append(errs, errors.New("subjects, resources and actions are required"))
+	}
+	var effect string
+	switch p.Effect {
+	case "allow":
+		effect = ladon.AllowAccess
+	case "deny":
+		effect = ladon.DenyAccess
+	default:
+		errs = append(errs, fmt.Errorf("unknown effect %q, expected allow or deny", p.Effect))
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	return m.Create(&ladon.DefaultPolicy{
+		ID:          p.ID,
+		Description: p.Description,
+		Subjects:    p.Subjects,
+		Resources:   p.Resources,
+		Actions:     p.Actions,
+		Effect:      effect,
+	})
+}
+
+func rbacResourceMapping(
+	resources []RBACResourceConfig,
+) (func(*http.Request) string, []error) {
+	type mapping struct {
+		path     func(*http.Request) bool
+		methods  func(*http.Request) bool
+		resource string
+	}
+
+	var (
+		mappings []mapping
+		errs     []error
+	)
+	for i, res := range resources {
+		if res.Path == "" || res.Resource == "" {
+			errs = append(errs, fmt.Errorf(
+				"config: rbac.resources[%d]: path and resource are required", i,
+			))
+			continue
+		}
+		m := mapping{path: PathGlob(res.Path), resource: res.Resource}
+		if len(res.Methods) > 0 {
+			m.methods = Methods(res.Methods...)
+		}
+		mappings = append(mappings, m)
+	}
+
+	return func(r *http.Request) string {
+		for _, m := range mappings {
+			if m.path(r) && (m.methods == nil || m.methods(r)) {
+				return m.resource
+			}
+		}
+
+		return r.RequestURI
+	}, errs
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
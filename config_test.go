@@ -0,0 +1,289 @@
+package gohttpmw
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const testConfigYAML = `
+request_id:
+  enabled: true
+real_ip:
+  trusted_proxies: ["10.0.0.0/8"]
+  header: forwarded
+logging:
+  enabled: true
+  level: warn
+  fields:
+    service: fish
+security:
+  enabled: true
+  headers:
+    Strict-Transport-Security: max-age=63072000
+csp:
+  directives:
+    default-src: "'self'"
+    img-src: "'self' cdn.example.com"
+cors:
+  allowed_origins: ["https://*.example.com"]
+  allow_credentials: true
+  max_age: 10m
+rate_limit:
+  limit: 2
+  period: 1m
+rbac:
+  policies:
+    - id: admin
+      subjects: ["admin"]
+      resources: ["users"]
+      actions: ["GET", "DELETE"]
+      effect: allow
+  resources:
+    - path: /users/**
+      resource: users
+`
+
+func TestConfigUnmarshal(t *testing.T) {
+	var fromYAML Config
+	if err := yaml.Unmarshal([]byte(testConfigYAML), &fromYAML); err != nil {
+		t.Fatalf("yaml.Unmarshal failed %v", err)
+	}
+	if time.Duration(fromYAML.CORS.MaxAge) != 10*time.Minute {
+		t.Errorf("expected max age of 10m, got %v", time.Duration(fromYAML.CORS.MaxAge))
+	}
+	if fromYAML.RBAC.Policies[0].Effect != "allow" {
+		t.Errorf("expected the rbac policies to be read, got %+v", fromYAML.RBAC)
+	}
+
+	b, err := json.Marshal(fromYAML)
+	if err != nil {
+		t.Fatalf("json.Marshal failed %v", err)
+	}
+	var fromJSON Config
+	if err := json.Unmarshal(b, &fromJSON); err != nil {
+		t.Fatalf("json.Unmarshal failed %v", err)
+	}
+	if fromJSON.RateLimit != fromYAML.RateLimit {
+		t.Errorf("expected %+v, got %+v", fromYAML.RateLimit, fromJSON.RateLimit)
+	}
+}
+
+// testBuildAuth authenticates the users with the password secret,
+// their name is their role
+func testBuildAuth() Middleware {
+	return BasicAuth("test", basicAuthValidatorFunc(
+		func(user, password string) (*Principal, error) {
+			if password != "secret" {
+				return nil, ErrBasicAuthInvalid
+			}
+			return &Principal{ID: user, Roles: []string{user}}, nil
+		},
+	))
+}
+
+func TestBuild(t *testing.T) {
+	var cfg Config
+	if err := yaml.Unmarshal([]byte(testConfigYAML), &cfg); err != nil {
+		t.Fatalf("yaml.Unmarshal failed %v", err)
+	}
+	mw, err := Build(cfg, WithAuth(testBuildAuth()))
+	if err != nil {
+		t.Fatalf("Build failed %v", err)
+	}
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rr := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	request.Header.Set("Origin", "https://app.example.com")
+	request.SetBasicAuth("admin", "secret")
+	handler.ServeHTTP(rr, request)
+
+	expectedHeaders := map[string]string{
+		"Strict-Transport-Security":        "max-age=63072000",
+		"X-Frame-Options":                  "SAMEORIGIN",
+		"Content-Security-Policy":          "default-src 'self'; img-src 'self' cdn.example.com",
+		"Access-Control-Allow-Origin":      "https://app.example.com",
+		"Access-Control-Allow-Credentials": "true",
+		"RateLimit-Remaining":              "1",
+	}
+	for k, v := range expectedHeaders {
+		if got := rr.Header().Get(k); got != v {
+			t.Errorf("expected header %s: %q, got %q", k, v, got)
+		}
+	}
+	if rr.Header().Get("requestID") == "" {
+		t.Errorf("expected a request id")
+	}
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected RBAC to allow the admin, got %d", rr.Code)
+	}
+}
+
+func TestBuildRBACResources(t *testing.T) {
+	var cfg Config
+	if err := yaml.Unmarshal([]byte(testConfigYAML), &cfg); err != nil {
+		t.Fatalf("yaml.Unmarshal failed %v", err)
+	}
+	mw, err := Build(Config{RBAC: cfg.RBAC}, WithAuth(testBuildAuth()))
+	if err != nil {
+		t.Fatalf("Build failed %v", err)
+	}
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for path, expected := range map[string]int{
+		"/users/1":  http.StatusOK,
+		"/orders/1": http.StatusForbidden,
+	} {
+		rr := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodDelete, path, nil)
+		request.SetBasicAuth("admin", "secret")
+		handler.ServeHTTP(rr, request)
+		if rr.Code != expected {
+			t.Errorf("%s: expected status %d, got %d", path, expected, rr.Code)
+		}
+	}
+}
+
+func TestBuildWithoutAuth(t *testing.T) {
+	var cfg Config
+	if err := yaml.Unmarshal([]byte(testConfigYAML), &cfg); err != nil {
+		t.Fatalf("yaml.Unmarshal failed %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		cfg           Config
+		expectedField string
+	}{
+		{
+			name:          "rbac policies",
+			cfg:           Config{RBAC: cfg.RBAC},
+			expectedField: "rbac.policies",
+		},
+		{
+			name:          "rate limit by principal",
+			cfg:           Config{RateLimit: RateLimitConfig{Limit: 2, Period: cfg.RateLimit.Period, Key: "principal"}},
+			expectedField: "rate_limit.key",
+		},
+		{
+			name:          "rate limit by api key",
+			cfg:           Config{RateLimit: RateLimitConfig{Limit: 2, Period: cfg.RateLimit.Period, Key: "apikey"}},
+			expectedField: "rate_limit.key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Build(tt.cfg)
+			if err == nil || !strings.Contains(err.Error(), "config: "+tt.expectedField+":") {
+				t.Errorf("expected a %s error without authentication, got %v", tt.expectedField, err)
+			}
+		})
+	}
+}
+
+func TestBuildWithAuth(t *testing.T) {
+	var cfg Config
+	if err := yaml.Unmarshal([]byte(testConfigYAML), &cfg); err != nil {
+		t.Fatalf("yaml.Unmarshal failed %v", err)
+	}
+
+	tests := []struct {
+		name             string
+		key              string
+		user             string
+		password         string
+		expectedStatuses []int
+	}{
+		{
+			name:             "admin",
+			key:              "principal",
+			user:             "admin",
+			password:         "secret",
+			expectedStatuses: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			name:             "other role",
+			key:              "principal",
+			user:             "guest",
+			password:         "secret",
+			expectedStatuses: []int{http.StatusForbidden},
+		},
+		{
+			name:             "wrong password throttled by ip",
+			key:              "ip",
+			user:             "admin",
+			password:         "guess",
+			expectedStatuses: []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.RateLimit.Key = tt.key
+			mw, err := Build(cfg, WithAuth(testBuildAuth()))
+			if err != nil {
+				t.Fatalf("Build failed %v", err)
+			}
+			handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			for i, expected := range tt.expectedStatuses {
+				rr := httptest.NewRecorder()
+				request := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+				request.SetBasicAuth(tt.user, tt.password)
+				handler.ServeHTTP(rr, request)
+
+				if rr.Code != expected {
+					t.Errorf("request %d: expected status %d, got %d", i, expected, rr.Code)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	cfg := Config{
+		RealIP:  RealIPConfig{TrustedProxies: []string{"10.0.0.0/33"}, Header: "X-Client-IP"},
+		Logging: LoggingConfig{Enabled: true, Format: "xml", Level: "loud"},
+		CSP:     CSPConfig{Directives: map[string]string{"Default Src": "'self'"}},
+		CORS: CORSConfig{
+			AllowedOrigins:   []string{"*", "example.com"},
+			AllowCredentials: true,
+		},
+		RateLimit: RateLimitConfig{Algorithm: "leaky", Key: "user"},
+		RBAC: RBACConfig{
+			Policies:  []RBACPolicyConfig{{ID: "p", Effect: "maybe"}},
+			Resources: []RBACResourceConfig{{Path: "/users"}},
+		},
+	}
+
+	_, err := Build(cfg)
+	if err == nil {
+		t.Fatalf("expected errors")
+	}
+	for _, field := range []string{
+		"real_ip.trusted_proxies",
+		"real_ip.header",
+		"logging.format",
+		"logging.level",
+		"csp.directives",
+		"cors.allowed_origins[1]",
+		"cors.allow_credentials",
+		"rate_limit.limit",
+		"rate_limit.period",
+		"rate_limit.algorithm",
+		"rate_limit.key",
+		"rbac.policies[0]",
+		"rbac.resources[0]",
+	} {
+		if !strings.Contains(err.Error(), "config: "+field+":") {
+			t.Errorf("expected an error for %s, got\n%v", field, err)
+		}
+	}
+}
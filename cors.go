@@ -0,0 +1,158 @@
+package gohttpmw
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrCORSDenied is recorded as the request error of the preflight requests
+// from an origin or for a method that is not allowed
+var ErrCORSDenied = errors.New("cors: preflight denied")
+
+// CORSOptions configures the CORS middleware
+type CORSOptions struct {
+	// AllowedOrigins are origins such as https://example.com,
+	// https://*.example.com for the subdomains or * for all
+	AllowedOrigins []string
+	// AllowedMethods are GET, HEAD and POST by default
+	AllowedMethods []string
+	// AllowedHeaders are Accept, Content-Type and X-Requested-With
+	// by default, * allows all
+	AllowedHeaders []string
+	ExposedHeaders []string
+	// AllowCredentials is never sent to the origins allowed only by *,
+	// they get an Access-Control-Allow-Origin of * instead
+	AllowCredentials bool
+	// MaxAge is how long the preflight responses can be cached
+	MaxAge time.Duration
+}
+
+// CORS answers the preflight requests and adds the CORS headers
+// to the responses to the allowed origins
+func CORS(o CORSOptions) func(http.Handler) http.Handler {
+	methods := o.AllowedMethods
+	if len(methods) == 0 {
+		methods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+	}
+	headers := o.AllowedHeaders
+	if len(headers) == 0 {
+		headers = []string{"Accept", "Content-Type", "X-Requested-With"}
+	}
+	allowAllHeaders := false
+	for _, h := range headers {
+		allowAllHeaders = allowAllHeaders || h == "*"
+	}
+	// The origins credentials can be allowed for, all but *
+	var credentialOrigins []string
+	if o.AllowCredentials {
+		for _, origin := range o.AllowedOrigins {
+			if origin != "*" {
+				credentialOrigins = append(credentialOrigins, origin)
+			}
+		}
+	}
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Origin")
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions &&
+				r.Header.Get("Access-Control-Request-Method") != ""
+			if origin == "" {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			allowed := corsOriginAllowed(o.AllowedOrigins, origin)
+			credentials := corsOriginAllowed(credentialOrigins, origin)
+			if !preflight {
+				if allowed {
+					o.setOriginHeaders(w, origin, credentials)
+					if len(o.ExposedHeaders) > 0 {
+						w.Header().Set(
+							"Access-Control-Expose-Headers",
+							strings.Join(o.ExposedHeaders, ", "),
+						)
+					}
+				}
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+			reqHeaders := splitHeaderList(r.Header.Values("Access-Control-Request-Headers"))
+			if !allowed ||
+				!containsFold(methods, r.Header.Get("Access-Control-Request-Method")) ||
+				(!allowAllHeaders && !containsAllFold(headers, reqHeaders)) {
+				SetRequestError(r, ErrCORSDenied)
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			o.setOriginHeaders(w, origin, credentials)
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+			if len(reqHeaders) > 0 {
+				w.Header().Set("Access-Control-Allow-Headers", strings.Join(reqHeaders, ", "))
+			}
+			if o.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(o.MaxAge.Seconds())))
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+// setOriginHeaders allows origin, with credentials when it is allowed
+// by an entry other than *, which would let any site use them
+func (o *CORSOptions) setOriginHeaders(w http.ResponseWriter, origin string, credentials bool) {
+	if !credentials && containsFold(o.AllowedOrigins, "*") {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	if credentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func corsOriginAllowed(allowed []string, origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, a := range allowed {
+		a = strings.ToLower(a)
+		if a == "*" || a == origin {
+			return true
+		}
+		// https://*.example.com
+		if scheme, host, ok := strings.Cut(a, "://*."); ok &&
+			strings.HasPrefix(origin, scheme+"://") &&
+			strings.HasSuffix(origin, "."+host) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsAllFold(list []string, values []string) bool {
+	for _, v := range values {
+		if !containsFold(list, v) {
+			return false
+		}
+	}
+
+	return true
+}
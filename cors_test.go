@@ -0,0 +1,161 @@
+package gohttpmw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCORS(t *testing.T) {
+	fakeHandler := http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {},
+	)
+
+	tests := []struct {
+		name            string
+		opts            CORSOptions
+		method          string
+		headers         map[string]string
+		expectedStatus  int
+		expectedHeaders map[string]string
+	}{
+		{
+			name:           "no origin",
+			opts:           CORSOptions{AllowedOrigins: []string{"https://example.com"}},
+			method:         http.MethodGet,
+			expectedStatus: http.StatusOK,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+		},
+		{
+			name:           "allowed origin",
+			opts:           CORSOptions{AllowedOrigins: []string{"https://example.com"}, ExposedHeaders: []string{"RateLimit-Remaining"}},
+			method:         http.MethodGet,
+			headers:        map[string]string{"Origin": "https://example.com"},
+			expectedStatus: http.StatusOK,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":   "https://example.com",
+				"Access-Control-Expose-Headers": "RateLimit-Remaining",
+			},
+		},
+		{
+			name:           "all origins",
+			opts:           CORSOptions{AllowedOrigins: []string{"*"}},
+			method:         http.MethodGet,
+			headers:        map[string]string{"Origin": "https://example.com"},
+			expectedStatus: http.StatusOK,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "*",
+			},
+		},
+		{
+			name:           "all origins with credentials",
+			opts:           CORSOptions{AllowedOrigins: []string{"*"}, AllowCredentials: true},
+			method:         http.MethodGet,
+			headers:        map[string]string{"Origin": "https://evil.example.org"},
+			expectedStatus: http.StatusOK,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "*",
+				"Access-Control-Allow-Credentials": "",
+			},
+		},
+		{
+			name: "listed origin with credentials next to all origins",
+			opts: CORSOptions{
+				AllowedOrigins:   []string{"*", "https://app.example.com"},
+				AllowCredentials: true,
+			},
+			method:         http.MethodGet,
+			headers:        map[string]string{"Origin": "https://app.example.com"},
+			expectedStatus: http.StatusOK,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Credentials": "true",
+			},
+		},
+		{
+			name:           "other origin",
+			opts:           CORSOptions{AllowedOrigins: []string{"https://*.example.com"}},
+			method:         http.MethodGet,
+			headers:        map[string]string{"Origin": "https://example.org"},
+			expectedStatus: http.StatusOK,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+		},
+		{
+			name: "preflight",
+			opts: CORSOptions{
+				AllowedOrigins:   []string{"https://*.example.com"},
+				AllowedMethods:   []string{http.MethodGet, http.MethodDelete},
+				AllowedHeaders:   []string{"Authorization"},
+				AllowCredentials: true,
+				MaxAge:           10 * time.Minute,
+			},
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                         "https://app.example.com",
+				"Access-Control-Request-Method":  http.MethodDelete,
+				"Access-Control-Request-Headers": "authorization",
+			},
+			expectedStatus: http.StatusNoContent,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://app.example.com",
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Allow-Methods":     "GET, DELETE",
+				"Access-Control-Allow-Headers":     "authorization",
+				"Access-Control-Max-Age":           "600",
+			},
+		},
+		{
+			name:   "preflight with method not allowed",
+			opts:   CORSOptions{AllowedOrigins: []string{"https://example.com"}},
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                        "https://example.com",
+				"Access-Control-Request-Method": http.MethodDelete,
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+		},
+		{
+			name:   "preflight with header not allowed",
+			opts:   CORSOptions{AllowedOrigins: []string{"https://example.com"}},
+			method: http.MethodOptions,
+			headers: map[string]string{
+				"Origin":                         "https://example.com",
+				"Access-Control-Request-Method":  http.MethodPost,
+				"Access-Control-Request-Headers": "X-Fish",
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			request := httptest.NewRequest(tt.method, `/`, nil)
+			for k, v := range tt.headers {
+				request.Header.Set(k, v)
+			}
+			CORS(tt.opts)(fakeHandler).ServeHTTP(rr, request)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			for k, v := range tt.expectedHeaders {
+				if got := rr.Header().Get(k); got != v {
+					t.Errorf("expected header %s: %q, got %q", k, v, got)
+				}
+			}
+			if tt.expectedStatus == http.StatusForbidden &&
+				GetRequestError(request.Context()) != ErrCORSDenied {
+				t.Errorf("expected request error %v", ErrCORSDenied)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package gohttpmw
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	// ErrUnsupportedEncoding is recorded as the request error of the requests
+	// with a body in an encoding that can't be decoded
+	ErrUnsupportedEncoding = errors.New("unsupported content encoding")
+	// ErrMalformedEncoding is recorded as the request error of the requests
+	// with a body that doesn't match its encoding
+	ErrMalformedEncoding = errors.New("malformed encoded body")
+)
+
+// ContextKeyDecompression stores the decompression stats of the request
+const ContextKeyDecompression = ContextKey("decompression")
+
+// decompressionStats are shared through the context with the loggers
+type decompressionStats struct {
+	encoding     string
+	compressed   *countingReadCloser
+	decompressed *countingReadCloser
+}
+
+// Decompress decodes the request bodies sent with a Content-Encoding of
+// gzip, deflate, br or zstd. Decoded bodies fail with ErrBodyTooLarge when
+// read past max bytes, to protect from decompression bombs, and the handler
+// is expected to respond with a 413. Other encodings get a 415 and bodies
+// that can't be decoded a 400, both recorded as request errors.
+// The loggers add the encoding and the compression ratio of the body read
+func Decompress(max int64) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			encoding := strings.ToLower(
+				strings.TrimSpace(r.Header.Get("Content-Encoding")),
+			)
+			if encoding == "" || encoding == "identity" ||
+				r.Body == nil || r.Body == http.NoBody {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			compressed := &countingReadCloser{ReadCloser: r.Body}
+			decoder, err := newBodyDecoder(encoding, compressed, max)
+			if err != nil {
+				if errors.Is(err, ErrUnsupportedEncoding) {
+					SetRequestError(r, ErrUnsupportedEncoding)
+					w.Header().Set("Accept-Encoding", strings.Join([]string{
+						EncodingGzip,
+						EncodingDeflate,
+						EncodingBrotli,
+						EncodingZstd,
+					}, ", "))
+					w.WriteHeader(http.StatusUnsupportedMediaType)
+					return
+				}
+				SetRequestError(r, ErrMalformedEncoding)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			limited := &limitedBody{ReadCloser: decoder, remaining: max}
+			stats := &decompressionStats{
+				encoding:     encoding,
+				compressed:   compressed,
+				decompressed: &countingReadCloser{ReadCloser: limited},
+			}
+
+			r.Body = stats.decompressed
+			r.ContentLength = -1
+			r.Header.Del("Content-Length")
+			r.Header.Del("Content-Encoding")
+			*r = *r.WithContext(
+				context.WithValue(r.Context(), ContextKeyDecompression, stats),
+			)
+
+			h.ServeHTTP(w, r)
+
+			if limited.exceeded {
+				SetRequestError(r, ErrBodyTooLarge)
+			}
+		})
+	}
+}
+
+// getDecompressionStats tells the encoding of the request body
+// and its compression ratio, from the bytes read by the handler
+func getDecompressionStats(ctx context.Context) (string, float64, bool) {
+	stats, ok := ctx.Value(ContextKeyDecompression).(*decompressionStats)
+	if !ok {
+		return "", 0, false
+	}
+	if stats.compressed.n == 0 {
+		return stats.encoding, 0, true
+	}
+
+	return stats.encoding,
+		float64(stats.decompressed.n) / float64(stats.compressed.n),
+		true
+}
+
+// decompressMaxWindow bounds the zstd window, the memory a frame can
+// claim before a byte is decoded. 8MB is what decoders should support
+const decompressMaxWindow = 8 << 20
+
+func newBodyDecoder(encoding string, body io.ReadCloser, limit int64) (io.ReadCloser, error) {
+	switch encoding {
+	case EncodingGzip, "x-gzip":
+		dec, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		return &decodedBody{Reader: dec, closers: []io.Closer{dec, body}}, nil
+	case EncodingDeflate:
+		dec, err := zlib.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		return &decodedBody{Reader: dec, closers: []io.Closer{dec, body}}, nil
+	case EncodingBrotli:
+		return &decodedBody{
+			Reader:  brotli.NewReader(body),
+			closers: []io.Closer{body},
+		}, nil
+	case EncodingZstd:
+		// The window, and so the memory, is no more than the body allowed
+		window := uint64(min(max(limit, zstd.MinWindowSize), decompressMaxWindow))
+		dec, err := zstd.NewReader(body,
+			zstd.WithDecoderConcurrency(1),
+			zstd.WithDecoderMaxWindow(window),
+			zstd.WithDecoderMaxMemory(window),
+		)
+		if err != nil {
+			return nil, err
+		}
+		return &decodedBody{
+			Reader:  dec,
+			closers: []io.Closer{dec.IOReadCloser(), body},
+		}, nil
+	default:
+		return nil, ErrUnsupportedEncoding
+	}
+}
+
+// decodedBody closes the decoder along with the original body
+type decodedBody struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (d *decodedBody) Close() error {
+	var err error
+	for _, c := range d.closers {
+		if cerr := c.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
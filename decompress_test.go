@@ -0,0 +1,214 @@
+package gohttpmw
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestDecompress(t *testing.T) {
+	payload := strings.Repeat(`{"fish":"fish"}`, 100)
+
+	tests := []struct {
+		name           string
+		encoding       string
+		body           []byte
+		max            int64
+		expectedStatus int
+		expectedBody   string
+		expectedErr    error
+	}{
+		{
+			name:           "not encoded",
+			body:           []byte(payload),
+			max:            10,
+			expectedStatus: http.StatusOK,
+			expectedBody:   payload,
+		},
+		{
+			name:           "gzip",
+			encoding:       EncodingGzip,
+			body:           encodeTestBody(t, EncodingGzip, payload),
+			max:            int64(len(payload)),
+			expectedStatus: http.StatusOK,
+			expectedBody:   payload,
+		},
+		{
+			name:           "deflate",
+			encoding:       EncodingDeflate,
+			body:           encodeTestBody(t, EncodingDeflate, payload),
+			max:            int64(len(payload)),
+			expectedStatus: http.StatusOK,
+			expectedBody:   payload,
+		},
+		{
+			name:           "brotli",
+			encoding:       EncodingBrotli,
+			body:           encodeTestBody(t, EncodingBrotli, payload),
+			max:            int64(len(payload)),
+			expectedStatus: http.StatusOK,
+			expectedBody:   payload,
+		},
+		{
+			name:           "zstd",
+			encoding:       EncodingZstd,
+			body:           encodeTestBody(t, EncodingZstd, payload),
+			max:            int64(len(payload)),
+			expectedStatus: http.StatusOK,
+			expectedBody:   payload,
+		},
+		{
+			name:           "decompressed body over the limit",
+			encoding:       EncodingGzip,
+			body:           encodeTestBody(t, EncodingGzip, payload),
+			max:            100,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedErr:    ErrBodyTooLarge,
+		},
+		{
+			name:           "unsupported encoding",
+			encoding:       "compress",
+			body:           []byte(payload),
+			max:            int64(len(payload)),
+			expectedStatus: http.StatusUnsupportedMediaType,
+			expectedErr:    ErrUnsupportedEncoding,
+		},
+		{
+			name:           "malformed gzip",
+			encoding:       EncodingGzip,
+			body:           []byte(payload),
+			max:            int64(len(payload)),
+			expectedStatus: http.StatusBadRequest,
+			expectedErr:    ErrMalformedEncoding,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeHandler := http.HandlerFunc(
+				func(w http.ResponseWriter, req *http.Request) {
+					if req.Header.Get("Content-Encoding") != "" {
+						t.Errorf("expected Content-Encoding to be removed")
+					}
+					b, err := io.ReadAll(req.Body)
+					if err != nil {
+						w.WriteHeader(http.StatusRequestEntityTooLarge)
+						return
+					}
+					_, _ = w.Write(b)
+				},
+			)
+			midWared := Decompress(tt.max)(fakeHandler)
+			rr := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodPost, `/`, bytes.NewReader(tt.body))
+			if tt.encoding != "" {
+				request.Header.Set("Content-Encoding", tt.encoding)
+			}
+			midWared.ServeHTTP(rr, request)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if tt.expectedBody != "" && rr.Body.String() != tt.expectedBody {
+				t.Errorf("expected body of %d bytes, got %d", len(tt.expectedBody), rr.Body.Len())
+			}
+			if err := GetRequestError(request.Context()); err != tt.expectedErr {
+				t.Errorf("expected request error %v, got %v", tt.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestDecompressLogger(t *testing.T) {
+	payload := strings.Repeat(`{"fish":"fish"}`, 100)
+	body := encodeTestBody(t, EncodingGzip, payload)
+	fakeHandler := http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			_, _ = io.ReadAll(req.Body)
+		},
+	)
+	logger, hook := test.NewNullLogger()
+	midWared := Logger(logger)(Decompress(1 << 20)(fakeHandler))
+
+	rr := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, `/`, bytes.NewReader(body))
+	request.Header.Set("Content-Encoding", "gzip")
+	midWared.ServeHTTP(rr, request)
+
+	data := hook.LastEntry().Data
+	if data["req_encoding"] != EncodingGzip {
+		t.Errorf("expected req_encoding gzip, got %v", data["req_encoding"])
+	}
+	expectedRatio := float64(len(payload)) / float64(len(body))
+	if data["req_compression_ratio"] != expectedRatio {
+		t.Errorf(
+			"expected req_compression_ratio %f, got %v",
+			expectedRatio, data["req_compression_ratio"],
+		)
+	}
+}
+
+func TestDecompressZstdWindow(t *testing.T) {
+	// A zstd frame declaring a 64MB window, with a single raw block
+	content := "small body claiming a large window"
+	frame := []byte{0x28, 0xb5, 0x2f, 0xfd, 0x00, (26 - 10) << 3}
+	header := len(content)<<3 | 1
+	frame = append(frame, byte(header), byte(header>>8), byte(header>>16))
+	frame = append(frame, content...)
+	buf := bytes.NewBuffer(frame)
+
+	var readErr error
+	handler := Decompress(1 << 30)(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			_, readErr = io.ReadAll(r.Body)
+		},
+	))
+	request := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(buf.Bytes()))
+	request.Header.Set("Content-Encoding", EncodingZstd)
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+
+	if !errors.Is(readErr, zstd.ErrWindowSizeExceeded) {
+		t.Errorf("expected %v, got %v", zstd.ErrWindowSizeExceeded, readErr)
+	}
+}
+
+func encodeTestBody(t *testing.T, encoding, s string) []byte {
+	t.Helper()
+
+	var (
+		buf bytes.Buffer
+		w   io.WriteCloser
+	)
+	switch encoding {
+	case EncodingGzip:
+		w = gzip.NewWriter(&buf)
+	case EncodingDeflate:
+		w = zlib.NewWriter(&buf)
+	case EncodingBrotli:
+		w = brotli.NewWriter(&buf)
+	case EncodingZstd:
+		enc, err := zstd.NewWriter(&buf)
+		if err != nil {
+			t.Fatalf("could not create zstd encoder: %v", err)
+		}
+		w = enc
+	}
+	if _, err := w.Write([]byte(s)); err != nil {
+		t.Fatalf("could not encode %s body: %v", encoding, err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("could not encode %s body: %v", encoding, err)
+	}
+
+	return buf.Bytes()
+}
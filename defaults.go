@@ -0,0 +1,110 @@
+package gohttpmw
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/ory/ladon"
+	"github.com/rs/zerolog"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultWebCSP is the CSP of WebStack when none is given
+const DefaultWebCSP = "default-src 'self'; frame-ancestors 'self'; object-src 'none'"
+
+// StackOptions configures the preset stacks, the middlewares
+// of the options left empty are not part of the stack
+type StackOptions struct {
+	// TrustedProxies enables RealIP, with the client info read from
+	// RealIPHeader, X-Forwarded-For when empty
+	TrustedProxies *TrustedProxies
+	RealIPHeader   RealIPHeader
+	// Logger, LoggerZero or LoggerSlog log the requests, only one of them
+	Logger     *logrus.Logger
+	LoggerZero *zerolog.Logger
+	LoggerSlog *slog.Logger
+	// CSP is the content security policy of WebStack,
+	// DefaultWebCSP when empty
+	CSP string
+	// CORS is applied before authentication so that preflight
+	// requests don't need credentials
+	CORS Middleware
+	// Auth authenticates the requests, such as JWTAuth,
+	// APIKeyAuth or BasicAuth
+	Auth Middleware
+	// Warden enables RBAC, with GetRole and RBACOptions
+	Warden      ladon.Warden
+	GetRole     func(context.Context) string
+	RBACOptions []RBACOption
+}
+
+// APIStack is the stack of the public APIs: request id, real ip, logging,
+// panic recovery, security headers, CORS, authentication and RBAC
+func APIStack(o StackOptions) (Chain, error) {
+	return o.stack(Security(), o.CORS)
+}
+
+// WebStack is the stack of the web applications, the APIStack
+// with a content security policy
+func WebStack(o StackOptions) (Chain, error) {
+	csp := o.CSP
+	if csp == "" {
+		csp = DefaultWebCSP
+	}
+
+	return o.stack(Security(), CSP(csp), o.CORS)
+}
+
+// InternalStack is the stack of the services only reachable from other
+// services: request id, real ip, logging, panic recovery,
+// authentication and RBAC
+func InternalStack(o StackOptions) (Chain, error) {
+	return o.stack()
+}
+
+// stack assembles the common middlewares around headers,
+// nil headers are skipped
+func (o StackOptions) stack(headers ...Middleware) (Chain, error) {
+	loggers := 0
+	for _, set := range []bool{o.Logger != nil, o.LoggerZero != nil, o.LoggerSlog != nil} {
+		if set {
+			loggers++
+		}
+	}
+	if loggers > 1 {
+		return Chain{}, errors.New("stack: Logger, LoggerZero and LoggerSlog are exclusive")
+	}
+
+	c := NewChain(RequestID())
+	if o.TrustedProxies != nil {
+		header := o.RealIPHeader
+		if header == "" {
+			header = RealIPXForwardedFor
+		}
+		c = c.Append(RealIP(o.TrustedProxies, WithRealIPHeader(header)))
+	}
+	switch {
+	case o.Logger != nil:
+		c = c.Append(Logger(o.Logger))
+	case o.LoggerZero != nil:
+		c = c.Append(LoggerZero(*o.LoggerZero))
+	case o.LoggerSlog != nil:
+		c = c.Append(LoggerSlog(o.LoggerSlog))
+	}
+	// Recover runs inside the logger so that panics are logged
+	c = c.Append(Recover())
+	for _, mw := range headers {
+		if mw != nil {
+			c = c.Append(mw)
+		}
+	}
+	if o.Auth != nil {
+		c = c.Append(o.Auth)
+	}
+	if o.Warden != nil {
+		c = c.Append(RBAC(o.Warden, o.GetRole, o.RBACOptions...))
+	}
+
+	return c, c.Validate()
+}
@@ -0,0 +1,112 @@
+package gohttpmw
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ory/ladon"
+	manager "github.com/ory/ladon/manager/memory"
+	"github.com/rs/zerolog"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestStacks(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	zl := zerolog.Nop()
+	tp, err := NewTrustedProxies("10.0.0.0/8")
+	if err != nil {
+		t.Fatalf("NewTrustedProxies failed %v", err)
+	}
+	full := StackOptions{
+		TrustedProxies: tp,
+		Logger:         logger,
+		CORS:           tagMiddleware("cors"),
+		Auth:           JWTAuth(StaticKey([]byte("secret"))),
+		Warden:         &ladon.Ladon{Manager: manager.NewMemoryManager()},
+	}
+
+	tests := []struct {
+		name     string
+		stack    func(StackOptions) (Chain, error)
+		opts     StackOptions
+		expected string
+	}{
+		{
+			name:     "api",
+			stack:    APIStack,
+			opts:     full,
+			expected: "RequestID RealIP Logger Recover Security tagMiddleware JWTAuth RBAC",
+		},
+		{
+			name:     "web",
+			stack:    WebStack,
+			opts:     full,
+			expected: "RequestID RealIP Logger Recover Security CSP tagMiddleware JWTAuth RBAC",
+		},
+		{
+			name:     "internal",
+			stack:    InternalStack,
+			opts:     full,
+			expected: "RequestID RealIP Logger Recover JWTAuth RBAC",
+		},
+		{
+			name:     "minimal api with zerolog",
+			stack:    APIStack,
+			opts:     StackOptions{LoggerZero: &zl},
+			expected: "RequestID LoggerZero Recover Security",
+		},
+		{
+			name:     "internal with slog",
+			stack:    InternalStack,
+			opts:     StackOptions{LoggerSlog: slog.Default()},
+			expected: "RequestID LoggerSlog Recover",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := tt.stack(tt.opts)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			names := make([]string, 0, len(c.mws))
+			for _, mw := range c.mws {
+				names = append(names, middlewareName(mw))
+			}
+			if got := strings.Join(names, " "); got != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestWebStackCSP(t *testing.T) {
+	c, err := WebStack(StackOptions{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	rr := httptest.NewRecorder()
+	c.ThenFunc(func(w http.ResponseWriter, r *http.Request) {}).
+		ServeHTTP(rr, httptest.NewRequest(http.MethodGet, `/`, nil))
+
+	if rr.Header().Get("Content-Security-Policy") != DefaultWebCSP {
+		t.Errorf("expected the default csp, got %q", rr.Header().Get("Content-Security-Policy"))
+	}
+	if rr.Header().Get("X-Frame-Options") == "" {
+		t.Errorf("expected the security headers")
+	}
+}
+
+func TestStackErrors(t *testing.T) {
+	logger, _ := test.NewNullLogger()
+	zl := zerolog.Nop()
+	if _, err := APIStack(StackOptions{Logger: logger, LoggerZero: &zl}); err == nil {
+		t.Errorf("expected an error with both loggers")
+	}
+	if _, err := APIStack(StackOptions{LoggerZero: &zl, LoggerSlog: slog.Default()}); err == nil {
+		t.Errorf("expected an error with both loggers")
+	}
+}
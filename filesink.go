@@ -0,0 +1,298 @@
+package gohttpmw
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrFileSinkClosed is returned by the writes to a closed FileSink
+var ErrFileSinkClosed = errors.New("filesink: closed")
+
+const fileSinkTimeFormat = "20060102T150405.000000000"
+
+// FileSink is an io.Writer for the loggers writing to a file, rotated
+// by size or time. Writes go to the file before Write returns and are
+// safe for concurrent use
+type FileSink struct {
+	name        string
+	maxSize     int64
+	rotateEvery time.Duration
+	maxBackups  int
+	compress    bool
+	signals     chan os.Signal
+	done        chan struct{}
+
+	mu       sync.Mutex
+	closed   bool
+	file     *os.File
+	size     int64
+	openedAt time.Time
+
+	// mill serializes the compression and removal of the rotated files
+	mill sync.Mutex
+	wg   sync.WaitGroup
+}
+
+// FileSinkOption configures a FileSink
+type FileSinkOption func(*FileSink)
+
+// WithMaxSize rotates the file before it grows over n bytes
+func WithMaxSize(n int64) FileSinkOption {
+	return func(s *FileSink) {
+		s.maxSize = n
+	}
+}
+
+// WithRotateEvery rotates the file every d, aligned on d,
+// such as every hour at the top of the hour
+func WithRotateEvery(d time.Duration) FileSinkOption {
+	return func(s *FileSink) {
+		s.rotateEvery = d
+	}
+}
+
+// WithMaxBackups keeps at most n rotated files, all are kept by default
+func WithMaxBackups(n int) FileSinkOption {
+	return func(s *FileSink) {
+		s.maxBackups = n
+	}
+}
+
+// WithCompress gzips the rotated files
+func WithCompress() FileSinkOption {
+	return func(s *FileSink) {
+		s.compress = true
+	}
+}
+
+// WithReopenOnSIGHUP reopens the file when the process receives a SIGHUP,
+// for external tools such as logrotate that move the file away
+func WithReopenOnSIGHUP() FileSinkOption {
+	return func(s *FileSink) {
+		s.signals = make(chan os.Signal, 1)
+	}
+}
+
+// NewFileSink opens, or creates, the named file for appending
+func NewFileSink(name string, opts ...FileSinkOption) (*FileSink, error) {
+	s := &FileSink{name: name, done: make(chan struct{})}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	if s.signals != nil {
+		signal.Notify(s.signals, syscall.SIGHUP)
+		go s.reopenOnSignal()
+	}
+
+	return s, nil
+}
+
+// Write writes p to the file, rotating it first when needed
+func (s *FileSink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return 0, ErrFileSinkClosed
+	}
+	if s.shouldRotate(int64(len(p))) {
+		_ = s.rotate()
+	}
+	n, err := s.file.Write(p)
+	s.size += int64(n)
+
+	return n, err
+}
+
+// Reopen closes and reopens the file
+func (s *FileSink) Reopen() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrFileSinkClosed
+	}
+
+	return s.reopenFile()
+}
+
+// Close closes the file,
+// it waits for the rotated files to be compressed
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrFileSinkClosed
+	}
+	s.closed = true
+	err := s.file.Close()
+	s.mu.Unlock()
+
+	if s.signals != nil {
+		signal.Stop(s.signals)
+	}
+	close(s.done)
+	s.wg.Wait()
+
+	return err
+}
+
+func (s *FileSink) reopenOnSignal() {
+	for {
+		select {
+		case <-s.signals:
+			_ = s.Reopen()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *FileSink) shouldRotate(n int64) bool {
+	if s.maxSize > 0 && s.size > 0 && s.size+n > s.maxSize {
+		return true
+	}
+	if s.rotateEvery > 0 &&
+		!time.Now().Before(s.openedAt.Truncate(s.rotateEvery).Add(s.rotateEvery)) {
+		return true
+	}
+
+	return false
+}
+
+// rotate moves the file away and opens a new one, when the rename
+// fails the writes go on to the current file
+func (s *FileSink) rotate() error {
+	if err := s.file.Close(); err != nil && !errors.Is(err, os.ErrClosed) {
+		return err
+	}
+	rotated := s.name + "." + time.Now().Format(fileSinkTimeFormat)
+	renameErr := os.Rename(s.name, rotated)
+	if err := s.open(); err != nil {
+		return err
+	}
+	if renameErr != nil {
+		return renameErr
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.mill.Lock()
+		defer s.mill.Unlock()
+
+		if s.compress {
+			_ = gzipFile(rotated)
+		}
+		s.removeOldBackups()
+	}()
+
+	return nil
+}
+
+// reopenFile opens the file again, even when the current one
+// was left closed by a failed rotation
+func (s *FileSink) reopenFile() error {
+	if err := s.file.Close(); err != nil && !errors.Is(err, os.ErrClosed) {
+		return err
+	}
+
+	return s.open()
+}
+
+func (s *FileSink) open() error {
+	f, err := os.OpenFile(s.name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	s.file = f
+	s.size = fi.Size()
+	s.openedAt = time.Now()
+
+	return nil
+}
+
+// removeOldBackups keeps the maxBackups most recent rotated files,
+// named after the file and the rotation time so that they sort by it.
+// The other files sharing the prefix, such as app.log.1.bak, are left alone
+func (s *FileSink) removeOldBackups() {
+	if s.maxBackups <= 0 {
+		return
+	}
+	matches, err := filepath.Glob(globEscape(s.name) + ".*")
+	if err != nil {
+		return
+	}
+	var backups []string
+	for _, m := range matches {
+		suffix := strings.TrimSuffix(strings.TrimPrefix(m, s.name+"."), ".gz")
+		if _, err := time.Parse(fileSinkTimeFormat, suffix); err == nil {
+			backups = append(backups, m)
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return strings.TrimSuffix(backups[i], ".gz") > strings.TrimSuffix(backups[j], ".gz")
+	})
+	for _, b := range backups[min(len(backups), s.maxBackups):] {
+		_ = os.Remove(b)
+	}
+}
+
+// globEscape escapes the characters of name that filepath.Match
+// would interpret
+func globEscape(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if strings.ContainsRune(`*?[\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+func gzipFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = src.Close() }()
+
+	dst, err := os.OpenFile(name+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(name)
+}
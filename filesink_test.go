@@ -0,0 +1,200 @@
+package gohttpmw
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestFileSinkRotation(t *testing.T) {
+	tests := []struct {
+		name            string
+		opts            []FileSinkOption
+		expectedBackups int
+		expectedSuffix  string
+	}{
+		{
+			name:            "size",
+			opts:            []FileSinkOption{WithMaxSize(20)},
+			expectedBackups: 4,
+		},
+		{
+			name:            "size and retention",
+			opts:            []FileSinkOption{WithMaxSize(20), WithMaxBackups(2)},
+			expectedBackups: 2,
+		},
+		{
+			name:            "compressed",
+			opts:            []FileSinkOption{WithMaxSize(20), WithMaxBackups(3), WithCompress()},
+			expectedBackups: 3,
+			expectedSuffix:  ".gz",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := filepath.Join(t.TempDir(), "access.log")
+			s, err := NewFileSink(name, tt.opts...)
+			if err != nil {
+				t.Fatalf("NewFileSink failed %v", err)
+			}
+			for i := 0; i < 5; i++ {
+				_, _ = s.Write([]byte("0123456789abcdef\n"))
+			}
+			if err := s.Close(); err != nil {
+				t.Fatalf("Close failed %v", err)
+			}
+
+			backups, _ := filepath.Glob(name + ".*")
+			if len(backups) != tt.expectedBackups {
+				t.Fatalf("expected %d backups, got %v", tt.expectedBackups, backups)
+			}
+			for _, b := range backups {
+				if !strings.HasSuffix(b, tt.expectedSuffix) {
+					t.Errorf("expected %s to end with %q", b, tt.expectedSuffix)
+				}
+				if got := readTestLog(t, b); got != "0123456789abcdef\n" {
+					t.Errorf("unexpected content of %s %q", b, got)
+				}
+			}
+			if got := readTestLog(t, name); got != "0123456789abcdef\n" {
+				t.Errorf("unexpected content of the current file %q", got)
+			}
+		})
+	}
+}
+
+func TestFileSinkBackupsRetention(t *testing.T) {
+	dir := t.TempDir()
+	// The name has glob metacharacters and siblings sharing its prefix
+	name := filepath.Join(dir, "app[1].log")
+	siblings := []string{name + ".1.bak", name + ".conf", name + ".20060102.gz"}
+	for _, sibling := range siblings {
+		if err := os.WriteFile(sibling, []byte("keep"), 0600); err != nil {
+			t.Fatalf("writing %s failed %v", sibling, err)
+		}
+	}
+
+	s, err := NewFileSink(name, WithMaxSize(20), WithMaxBackups(1))
+	if err != nil {
+		t.Fatalf("NewFileSink failed %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		_, _ = s.Write([]byte("0123456789abcdef\n"))
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close failed %v", err)
+	}
+
+	for _, sibling := range siblings {
+		if _, err := os.Stat(sibling); err != nil {
+			t.Errorf("expected %s to be kept, got %v", sibling, err)
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir failed %v", err)
+	}
+	// The current file, one backup and the siblings
+	if expected := 2 + len(siblings); len(entries) != expected {
+		t.Errorf("expected %d files, got %d", expected, len(entries))
+	}
+}
+
+func TestFileSinkRotateEvery(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "access.log")
+	s, err := NewFileSink(name, WithRotateEvery(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewFileSink failed %v", err)
+	}
+	_, _ = s.Write([]byte("first\n"))
+	time.Sleep(100 * time.Millisecond)
+	_, _ = s.Write([]byte("second\n"))
+	_ = s.Close()
+
+	backups, _ := filepath.Glob(name + ".*")
+	if len(backups) != 1 || readTestLog(t, backups[0]) != "first\n" {
+		t.Fatalf("expected the first write to be rotated, got %v", backups)
+	}
+	if got := readTestLog(t, name); got != "second\n" {
+		t.Errorf("unexpected content of the current file %q", got)
+	}
+}
+
+func TestFileSinkReopen(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "access.log")
+	s, err := NewFileSink(name)
+	if err != nil {
+		t.Fatalf("NewFileSink failed %v", err)
+	}
+	_, _ = s.Write([]byte("before\n"))
+	// As logrotate would
+	if err := os.Rename(name, filepath.Join(dir, "moved.log")); err != nil {
+		t.Fatalf("Rename failed %v", err)
+	}
+	if err := s.Reopen(); err != nil {
+		t.Fatalf("Reopen failed %v", err)
+	}
+	_, _ = s.Write([]byte("after\n"))
+	_ = s.Close()
+
+	if got := readTestLog(t, filepath.Join(dir, "moved.log")); got != "before\n" {
+		t.Errorf("unexpected content of the moved file %q", got)
+	}
+	if got := readTestLog(t, name); got != "after\n" {
+		t.Errorf("unexpected content of the reopened file %q", got)
+	}
+
+	if _, err := s.Write([]byte("closed\n")); err != ErrFileSinkClosed {
+		t.Errorf("expected %v, got %v", ErrFileSinkClosed, err)
+	}
+	if err := s.Reopen(); err != ErrFileSinkClosed {
+		t.Errorf("expected %v, got %v", ErrFileSinkClosed, err)
+	}
+}
+
+func TestFileSinkLoggerZero(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "access.log")
+	s, err := NewFileSink(name)
+	if err != nil {
+		t.Fatalf("NewFileSink failed %v", err)
+	}
+	logger := zerolog.New(s)
+	logger.Info().Str("from", "zerolog").Send()
+	_ = s.Close()
+
+	if got := readTestLog(t, name); got != `{"level":"info","from":"zerolog"}`+"\n" {
+		t.Errorf("unexpected log %q", got)
+	}
+}
+
+func readTestLog(t *testing.T, name string) string {
+	t.Helper()
+
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatalf("Open failed %v", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var r io.Reader = f
+	if strings.HasSuffix(name, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatalf("gzip.NewReader failed %v", err)
+		}
+		r = gz
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll failed %v", err)
+	}
+
+	return string(b)
+}
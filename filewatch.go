@@ -0,0 +1,96 @@
+package gohttpmw
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// ReloadOption configures how a file backed source reloads its file
+type ReloadOption func(*fileWatch)
+
+// WithReloadErrorHandler is called with the errors of the reloads,
+// the previous content of the file is kept when one fails
+func WithReloadErrorHandler(f func(error)) ReloadOption {
+	return func(fw *fileWatch) {
+		fw.onError = f
+	}
+}
+
+// fileWatch reloads a file when its size or modification time changes,
+// checking at most once every every
+type fileWatch struct {
+	name    string
+	every   time.Duration
+	load    func(*os.File) error
+	onError func(error)
+
+	mu        sync.Mutex
+	modTime   time.Time
+	size      int64
+	checkedAt time.Time
+}
+
+// newFileWatch loads the named file a first time with load
+func newFileWatch(
+	name string,
+	every time.Duration,
+	load func(*os.File) error,
+	opts ...ReloadOption,
+) (*fileWatch, error) {
+	fw := &fileWatch{name: name, every: every, load: load}
+	for _, opt := range opts {
+		opt(fw)
+	}
+	if err := fw.reload(); err != nil {
+		return nil, err
+	}
+
+	return fw, nil
+}
+
+// reloadIfChanged reloads the file if its size or modification time changed
+func (fw *fileWatch) reloadIfChanged() {
+	fw.mu.Lock()
+	if time.Since(fw.checkedAt) < fw.every {
+		fw.mu.Unlock()
+		return
+	}
+	fw.checkedAt = time.Now()
+	modTime, size := fw.modTime, fw.size
+	fw.mu.Unlock()
+
+	fi, err := os.Stat(fw.name)
+	if err == nil && fi.ModTime().Equal(modTime) && fi.Size() == size {
+		return
+	}
+	if err == nil {
+		err = fw.reload()
+	}
+	if err != nil && fw.onError != nil {
+		fw.onError(err)
+	}
+}
+
+func (fw *fileWatch) reload() error {
+	f, err := os.Open(fw.name)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if err := fw.load(f); err != nil {
+		return err
+	}
+
+	fw.mu.Lock()
+	fw.modTime = fi.ModTime()
+	fw.size = fi.Size()
+	fw.mu.Unlock()
+
+	return nil
+}
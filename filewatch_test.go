@@ -0,0 +1,64 @@
+package gohttpmw
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileWatchReloadError(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "iprules")
+	write := func(content string, modTime time.Time) {
+		if err := os.WriteFile(name, []byte(content), 0600); err != nil {
+			t.Fatalf("writing ip rules failed %v", err)
+		}
+		if err := os.Chtimes(name, modTime, modTime); err != nil {
+			t.Fatalf("changing ip rules times failed %v", err)
+		}
+	}
+	office := netip.MustParseAddr("192.0.2.10")
+
+	now := time.Now()
+	write("allow 192.0.2.0/24\n", now.Add(-time.Hour))
+	var errs []error
+	rules, err := NewIPRulesFromFile(
+		name, 0, WithReloadErrorHandler(func(err error) { errs = append(errs, err) }),
+	)
+	if err != nil {
+		t.Fatalf("NewIPRulesFromFile failed %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		content        string
+		remove         bool
+		modTime        time.Time
+		expectedErrors int
+	}{
+		{name: "unchanged", modTime: now.Add(-time.Hour), content: "allow 192.0.2.0/24\n", expectedErrors: 0},
+		{name: "invalid", modTime: now, content: "permit 192.0.2.0/24\n", expectedErrors: 1},
+		{name: "removed", remove: true, expectedErrors: 2},
+		{name: "fixed", modTime: now.Add(time.Hour), content: "allow 192.0.2.0/24\n", expectedErrors: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.remove {
+				if err := os.Remove(name); err != nil {
+					t.Fatalf("removing ip rules failed %v", err)
+				}
+			} else {
+				write(tt.content, tt.modTime)
+			}
+
+			if !rules.Allowed(office) {
+				t.Errorf("expected the office to stay allowed")
+			}
+			if len(errs) != tt.expectedErrors {
+				t.Errorf("expected %d reload errors, got %v", tt.expectedErrors, errs)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package gohttpmw
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// dummyHash is compared against for unknown users,
+// so that the response time doesn't reveal which users exist
+var dummyHash = []byte("$2a$10$ImbnawjE7Ggw/OkquG.uUe./6nx.M5PxBln2SJxkTXIXQPtU4at6q")
+
+// Htpasswd is a BasicAuthValidator backed by an htpasswd-style file,
+// the file is reloaded when it changes
+type Htpasswd struct {
+	watch *fileWatch
+
+	mu    sync.RWMutex
+	users map[string]htpasswdUser
+}
+
+type htpasswdUser struct {
+	// hash is bcrypt, or argon2 is set
+	hash   []byte
+	argon2 *argon2Hash
+	roles  []string
+}
+
+// NewHtpasswd loads the named htpasswd file, each line is
+// `user:hash` or `user:hash:comma separated roles` where hash is bcrypt
+// or argon2 in PHC format, empty lines and lines starting with # are
+// ignored. The file is checked for changes at most once every reloadEvery
+func NewHtpasswd(
+	name string,
+	reloadEvery time.Duration,
+	opts ...ReloadOption,
+) (*Htpasswd, error) {
+	h := &Htpasswd{}
+	watch, err := newFileWatch(name, reloadEvery, h.load, opts...)
+	if err != nil {
+		return nil, err
+	}
+	h.watch = watch
+
+	return h, nil
+}
+
+// Validate checks the password of user against its hash
+func (h *Htpasswd) Validate(user, password string) (*Principal, error) {
+	h.watch.reloadIfChanged()
+
+	h.mu.RLock()
+	u, ok := h.users[user]
+	h.mu.RUnlock()
+
+	if !ok {
+		_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, ErrBasicAuthInvalid
+	}
+	if !u.check(password) {
+		return nil, ErrBasicAuthInvalid
+	}
+
+	return &Principal{
+		ID:         user,
+		Roles:      u.roles,
+		AuthMethod: AuthMethodBasic,
+	}, nil
+}
+
+// load parses the file, the current users are kept if it can't be
+func (h *Htpasswd) load(f *os.File) error {
+	users := make(map[string]htpasswdUser)
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.SplitN(line, ":", 3)
+		if len(fields) < 2 || fields[0] == "" {
+			return fmt.Errorf("htpasswd: %s:%d: expected user:hash", f.Name(), lineNum)
+		}
+		u, err := parsePasswordHash(fields[1])
+		if err != nil {
+			return fmt.Errorf("htpasswd: %s:%d: %v", f.Name(), lineNum, err)
+		}
+		if len(fields) == 3 && fields[2] != "" {
+			u.roles = strings.Split(fields[2], ",")
+		}
+		users[fields[0]] = u
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	h.users = users
+	h.mu.Unlock()
+
+	return nil
+}
+
+// Bounds of the argon2 parameters, the memory is in KiB. They keep
+// a bad line from exhausting the server at every login
+const (
+	maxArgon2Memory     = 256 * 1024
+	maxArgon2Iterations = 64
+	minArgon2SaltLength = 8
+	minArgon2KeyLength  = 16
+)
+
+// argon2Hash is a PHC formatted argon2 hash such as
+// $argon2id$v=19$m=65536,t=3,p=4$salt$hash
+type argon2Hash struct {
+	variant    string
+	memory     uint32
+	iterations uint32
+	threads    uint8
+	salt       []byte
+	key        []byte
+}
+
+func parsePasswordHash(hash string) (htpasswdUser, error) {
+	if strings.HasPrefix(hash, "$argon2") {
+		a, err := parseArgon2Hash(hash)
+		if err != nil {
+			return htpasswdUser{}, err
+		}
+		return htpasswdUser{argon2: a}, nil
+	}
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(hash, prefix) {
+			if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+				return htpasswdUser{}, err
+			}
+			return htpasswdUser{hash: []byte(hash)}, nil
+		}
+	}
+
+	return htpasswdUser{}, errors.New("unsupported hash, only bcrypt and argon2")
+}
+
+func parseArgon2Hash(hash string) (*argon2Hash, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return nil, errors.New("argon2: expected $variant$v=$m=,t=,p=$salt$hash")
+	}
+	a := &argon2Hash{variant: parts[1]}
+	if a.variant != "argon2id" && a.variant != "argon2i" {
+		return nil, fmt.Errorf("argon2: unsupported variant %s", a.variant)
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil ||
+		version != argon2.Version {
+		return nil, fmt.Errorf("argon2: unsupported version %s", parts[2])
+	}
+
+	if _, err := fmt.Sscanf(
+		parts[3], "m=%d,t=%d,p=%d", &a.memory, &a.iterations, &a.threads,
+	); err != nil {
+		return nil, fmt.Errorf("argon2: parameters %s: %v", parts[3], err)
+	}
+	switch {
+	case a.threads < 1:
+		return nil, errors.New("argon2: p must be at least 1")
+	case a.iterations < 1 || a.iterations > maxArgon2Iterations:
+		return nil, fmt.Errorf("argon2: t must be between 1 and %d", maxArgon2Iterations)
+	case a.memory < 8*uint32(a.threads) || a.memory > maxArgon2Memory:
+		return nil, fmt.Errorf("argon2: m must be between 8*p and %d", maxArgon2Memory)
+	}
+
+	var err error
+	if a.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil ||
+		len(a.salt) < minArgon2SaltLength {
+		return nil, fmt.Errorf("argon2: salt must be at least %d base64 bytes", minArgon2SaltLength)
+	}
+	if a.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil ||
+		len(a.key) < minArgon2KeyLength {
+		return nil, fmt.Errorf("argon2: hash must be at least %d base64 bytes", minArgon2KeyLength)
+	}
+
+	return a, nil
+}
+
+func (u htpasswdUser) check(password string) bool {
+	if u.argon2 != nil {
+		return u.argon2.check(password)
+	}
+
+	return bcrypt.CompareHashAndPassword(u.hash, []byte(password)) == nil
+}
+
+func (a *argon2Hash) check(password string) bool {
+	derive := argon2.IDKey
+	if a.variant == "argon2i" {
+		derive = argon2.Key
+	}
+	key := derive(
+		[]byte(password), a.salt, a.iterations, a.memory, a.threads, uint32(len(a.key)),
+	)
+
+	return subtle.ConstantTimeCompare(key, a.key) == 1
+}
@@ -0,0 +1,179 @@
+package gohttpmw
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHtpasswd(t *testing.T) {
+	bcryptHash, _ := bcrypt.GenerateFromPassword([]byte("castor"), bcrypt.MinCost)
+	content := "# test users\n\n" +
+		"pollux:" + testArgon2Hash("s3cr3t") + ":admin,viewer\n" +
+		"castor:" + string(bcryptHash) + "\n"
+	name := filepath.Join(t.TempDir(), "htpasswd")
+	if err := os.WriteFile(name, []byte(content), 0600); err != nil {
+		t.Fatalf("writing htpasswd failed %v", err)
+	}
+
+	h, err := NewHtpasswd(name, 0)
+	if err != nil {
+		t.Fatalf("NewHtpasswd failed %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		user          string
+		password      string
+		expectedErr   error
+		expectedRoles []string
+	}{
+		{
+			name:          "argon2 user",
+			user:          "pollux",
+			password:      "s3cr3t",
+			expectedRoles: []string{"admin", "viewer"},
+		},
+		{
+			name:     "bcrypt user",
+			user:     "castor",
+			password: "castor",
+		},
+		{
+			name:        "wrong argon2 password",
+			user:        "pollux",
+			password:    "wrong",
+			expectedErr: ErrBasicAuthInvalid,
+		},
+		{
+			name:        "wrong bcrypt password",
+			user:        "castor",
+			password:    "wrong",
+			expectedErr: ErrBasicAuthInvalid,
+		},
+		{
+			name:        "unknown user",
+			user:        "helen",
+			password:    "s3cr3t",
+			expectedErr: ErrBasicAuthInvalid,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := h.Validate(tt.user, tt.password)
+			if err != tt.expectedErr {
+				t.Fatalf("expected error %v, got %v", tt.expectedErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if p.ID != tt.user || p.AuthMethod != AuthMethodBasic ||
+				!reflect.DeepEqual(p.Roles, tt.expectedRoles) {
+				t.Errorf("unexpected principal %+v", p)
+			}
+		})
+	}
+}
+
+func TestHtpasswdReload(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "htpasswd")
+	write := func(content string, modTime time.Time) {
+		if err := os.WriteFile(name, []byte(content), 0600); err != nil {
+			t.Fatalf("writing htpasswd failed %v", err)
+		}
+		if err := os.Chtimes(name, modTime, modTime); err != nil {
+			t.Fatalf("changing htpasswd times failed %v", err)
+		}
+	}
+
+	now := time.Now()
+	write("pollux:"+testArgon2Hash("old")+"\n", now.Add(-time.Hour))
+	h, err := NewHtpasswd(name, 0)
+	if err != nil {
+		t.Fatalf("NewHtpasswd failed %v", err)
+	}
+	if _, err := h.Validate("pollux", "old"); err != nil {
+		t.Fatalf("expected old password to be valid, got %v", err)
+	}
+
+	write("pollux:"+testArgon2Hash("new")+"\n", now)
+	if _, err := h.Validate("pollux", "new"); err != nil {
+		t.Errorf("expected new password after reload, got %v", err)
+	}
+	if _, err := h.Validate("pollux", "old"); err != ErrBasicAuthInvalid {
+		t.Errorf("expected old password to be rejected, got %v", err)
+	}
+
+	// A broken file keeps the current users
+	write("pollux:plaintext\n", now.Add(time.Hour))
+	if _, err := h.Validate("pollux", "new"); err != nil {
+		t.Errorf("expected users to be kept on reload error, got %v", err)
+	}
+}
+
+func TestNewHtpasswdErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "missing hash", content: "pollux\n"},
+		{name: "plain text password", content: "pollux:s3cr3t\n"},
+		{name: "md5 hash", content: "pollux:$apr1$salt$hash\n"},
+		{name: "broken bcrypt hash", content: "pollux:$2a$10$short\n"},
+		{
+			name:    "argon2 without parallelism",
+			content: "pollux:$argon2id$v=19$m=65536,t=1,p=0$c2FsdHNhbHQ$" + testArgon2Key + "\n",
+		},
+		{
+			name:    "argon2 with too much memory",
+			content: "pollux:$argon2id$v=19$m=4294967295,t=1,p=1$c2FsdHNhbHQ$" + testArgon2Key + "\n",
+		},
+		{
+			name:    "argon2 with too many iterations",
+			content: "pollux:$argon2id$v=19$m=65536,t=100000,p=1$c2FsdHNhbHQ$" + testArgon2Key + "\n",
+		},
+		{
+			name:    "argon2 with an undecodable salt",
+			content: "pollux:$argon2id$v=19$m=65536,t=1,p=1$not*base64$" + testArgon2Key + "\n",
+		},
+		{
+			name:    "argon2 with a short hash",
+			content: "pollux:$argon2id$v=19$m=65536,t=1,p=1$c2FsdHNhbHQ$c2hvcnQ\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := filepath.Join(t.TempDir(), "htpasswd")
+			if err := os.WriteFile(name, []byte(tt.content), 0600); err != nil {
+				t.Fatalf("writing htpasswd failed %v", err)
+			}
+			if _, err := NewHtpasswd(name, 0); err == nil {
+				t.Errorf("expected an error, got nothing")
+			}
+		})
+	}
+}
+
+// testArgon2Key is a 32 bytes argon2 key, base64 encoded
+const testArgon2Key = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY"
+
+func testArgon2Hash(password string) string {
+	salt := make([]byte, 16)
+	_, _ = rand.Read(salt)
+	key := argon2.IDKey([]byte(password), salt, 1, 64*1024, 2, 32)
+
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, 64*1024, 1, 2,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+}
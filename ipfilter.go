@@ -0,0 +1,152 @@
+package gohttpmw
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrIPDenied is recorded as the request error of the requests
+// rejected by IPFilter
+var ErrIPDenied = errors.New("ipfilter: ip address denied")
+
+// IPRules are allow and deny lists of networks, deny takes precedence
+// and when the allow list is empty, all the other ips are allowed
+type IPRules struct {
+	// watch is nil when the rules aren't loaded from a file
+	watch *fileWatch
+
+	mu       sync.RWMutex
+	allow    *ipTrie
+	deny     *ipTrie
+	hasAllow bool
+}
+
+// NewIPRules parses the allow and deny lists of CIDRs,
+// single addresses are accepted as well
+func NewIPRules(allow, deny []string) (*IPRules, error) {
+	ru := &IPRules{}
+	lines := make([]string, 0, len(allow)+len(deny))
+	for _, cidr := range allow {
+		lines = append(lines, "allow "+cidr)
+	}
+	for _, cidr := range deny {
+		lines = append(lines, "deny "+cidr)
+	}
+	if lineNum, err := ru.set(lines); err != nil {
+		return nil, fmt.Errorf("ipfilter: %q: %w", lines[lineNum-1], err)
+	}
+
+	return ru, nil
+}
+
+// NewIPRulesFromFile loads the named file, each line is `allow <cidr>`
+// or `deny <cidr>`, empty lines and lines starting with # are ignored.
+// The file is checked for changes at most once every reloadEvery
+func NewIPRulesFromFile(
+	name string,
+	reloadEvery time.Duration,
+	opts ...ReloadOption,
+) (*IPRules, error) {
+	ru := &IPRules{}
+	watch, err := newFileWatch(name, reloadEvery, ru.load, opts...)
+	if err != nil {
+		return nil, err
+	}
+	ru.watch = watch
+
+	return ru, nil
+}
+
+// Allowed tells if ip passes the rules
+func (ru *IPRules) Allowed(ip netip.Addr) bool {
+	if ru.watch != nil {
+		ru.watch.reloadIfChanged()
+	}
+
+	ru.mu.RLock()
+	defer ru.mu.RUnlock()
+
+	if ru.deny.contains(ip) {
+		return false
+	}
+
+	return !ru.hasAllow || ru.allow.contains(ip)
+}
+
+// IPFilter rejects the requests from the clients not allowed by rules
+// with a 403, recorded as request errors. The client ip is the one
+// resolved by RealIP when used before
+func IPFilter(rules *IPRules) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip, err := netip.ParseAddr(clientIP(r))
+			if err != nil || !rules.Allowed(ip) {
+				SetRequestError(r, ErrIPDenied)
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// load parses the file, the current rules are kept if it can't be
+func (ru *IPRules) load(f *os.File) error {
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if lineNum, err := ru.set(lines); err != nil {
+		return fmt.Errorf("ipfilter: %s:%d: %w", f.Name(), lineNum, err)
+	}
+
+	return nil
+}
+
+// set parses the rules and replaces the current ones if they are all valid,
+// it returns the number of the invalid line otherwise
+func (ru *IPRules) set(lines []string) (int, error) {
+	allow, deny, hasAllow := newIPTrie(), newIPTrie(), false
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return i + 1, errors.New("expected allow|deny <cidr>")
+		}
+		prefix, err := parsePrefix(fields[1])
+		if err != nil {
+			return i + 1, err
+		}
+		switch fields[0] {
+		case "allow":
+			allow.insert(prefix)
+			hasAllow = true
+		case "deny":
+			deny.insert(prefix)
+		default:
+			return i + 1, fmt.Errorf("unknown rule %q", fields[0])
+		}
+	}
+
+	ru.mu.Lock()
+	ru.allow, ru.deny, ru.hasAllow = allow, deny, hasAllow
+	ru.mu.Unlock()
+
+	return 0, nil
+}
@@ -0,0 +1,128 @@
+package gohttpmw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIPFilter(t *testing.T) {
+	rules, err := NewIPRules(
+		[]string{"10.0.0.0/8", "fd00::/8"},
+		[]string{"10.0.0.66"},
+	)
+	if err != nil {
+		t.Fatalf("NewIPRules failed %v", err)
+	}
+	fakeHandler := http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {},
+	)
+
+	tests := []struct {
+		name           string
+		remoteAddr     string
+		expectedStatus int
+	}{
+		{name: "allowed ipv4", remoteAddr: "10.1.2.3:1234", expectedStatus: http.StatusOK},
+		{name: "allowed ipv6", remoteAddr: "[fd00::1]:1234", expectedStatus: http.StatusOK},
+		{name: "denied in allowed range", remoteAddr: "10.0.0.66:1234", expectedStatus: http.StatusForbidden},
+		{name: "not allowed", remoteAddr: "203.0.113.1:1234", expectedStatus: http.StatusForbidden},
+		{name: "unparsable", remoteAddr: "pipe", expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, `/`, nil)
+			request.RemoteAddr = tt.remoteAddr
+			IPFilter(rules)(fakeHandler).ServeHTTP(rr, request)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			err := GetRequestError(request.Context())
+			if tt.expectedStatus == http.StatusForbidden && err != ErrIPDenied {
+				t.Errorf("expected request error %v, got %v", ErrIPDenied, err)
+			}
+		})
+	}
+}
+
+func TestIPFilterRealIP(t *testing.T) {
+	rules, err := NewIPRules(nil, []string{"203.0.113.0/24"})
+	if err != nil {
+		t.Fatalf("NewIPRules failed %v", err)
+	}
+	tp, err := NewTrustedProxies("10.0.0.0/8")
+	if err != nil {
+		t.Fatalf("NewTrustedProxies failed %v", err)
+	}
+	fakeHandler := http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {},
+	)
+
+	rr := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, `/`, nil)
+	request.RemoteAddr = "10.0.0.1:1234"
+	request.Header.Set("X-Forwarded-For", "203.0.113.1")
+	RealIP(tp)(IPFilter(rules)(fakeHandler)).ServeHTTP(rr, request)
+
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("expected the forwarded client to be denied, got %d", rr.Code)
+	}
+}
+
+func TestIPRulesReload(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "iprules")
+	write := func(content string, modTime time.Time) {
+		if err := os.WriteFile(name, []byte(content), 0600); err != nil {
+			t.Fatalf("writing ip rules failed %v", err)
+		}
+		if err := os.Chtimes(name, modTime, modTime); err != nil {
+			t.Fatalf("changing ip rules times failed %v", err)
+		}
+	}
+	office := netip.MustParseAddr("192.0.2.10")
+	vpn := netip.MustParseAddr("198.51.100.10")
+
+	now := time.Now()
+	write("# office\nallow 192.0.2.0/24\n", now.Add(-time.Hour))
+	rules, err := NewIPRulesFromFile(name, 0)
+	if err != nil {
+		t.Fatalf("NewIPRulesFromFile failed %v", err)
+	}
+	if !rules.Allowed(office) || rules.Allowed(vpn) {
+		t.Fatalf("expected only the office to be allowed")
+	}
+
+	write("allow 192.0.2.0/24\nallow 198.51.100.0/24\n", now)
+	if !rules.Allowed(office) || !rules.Allowed(vpn) {
+		t.Errorf("expected the office and the vpn to be allowed after reload")
+	}
+
+	// A broken file keeps the current rules
+	write("allow 198.51.100.0/24\npermit 192.0.2.0/24\n", now.Add(time.Hour))
+	if !rules.Allowed(office) {
+		t.Errorf("expected rules to be kept on reload error")
+	}
+}
+
+func TestNewIPRulesErrors(t *testing.T) {
+	if _, err := NewIPRules([]string{"10.0.0.0/8", "10.0.0.0/99"}, nil); err == nil {
+		t.Errorf("expected an error for an invalid cidr")
+	}
+
+	name := filepath.Join(t.TempDir(), "iprules")
+	for _, content := range []string{"allow\n", "permit 10.0.0.0/8\n", "deny 10.0.0.x\n"} {
+		if err := os.WriteFile(name, []byte(content), 0600); err != nil {
+			t.Fatalf("writing ip rules failed %v", err)
+		}
+		if _, err := NewIPRulesFromFile(name, 0); err == nil {
+			t.Errorf("expected an error for %q", content)
+		}
+	}
+}
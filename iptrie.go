@@ -0,0 +1,63 @@
+package gohttpmw
+
+import "net/netip"
+
+// ipTrie is a binary prefix tree of networks, looking an ip up costs
+// at most one step per bit of the address whatever the number of networks
+type ipTrie struct {
+	v4 *ipTrieNode
+	v6 *ipTrieNode
+}
+
+type ipTrieNode struct {
+	children [2]*ipTrieNode
+	// end marks the last bit of a network
+	end bool
+}
+
+func newIPTrie() *ipTrie {
+	return &ipTrie{v4: &ipTrieNode{}, v6: &ipTrieNode{}}
+}
+
+func (t *ipTrie) insert(prefix netip.Prefix) {
+	node := t.root(prefix.Addr())
+	b := prefix.Addr().AsSlice()
+	for i := 0; i < prefix.Bits(); i++ {
+		bit := ipBit(b, i)
+		if node.children[bit] == nil {
+			node.children[bit] = &ipTrieNode{}
+		}
+		node = node.children[bit]
+	}
+	node.end = true
+}
+
+// contains tells if ip belongs to one of the networks
+func (t *ipTrie) contains(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	node := t.root(ip)
+	b := ip.AsSlice()
+	for i := 0; node != nil; i++ {
+		if node.end {
+			return true
+		}
+		if i == ip.BitLen() {
+			return false
+		}
+		node = node.children[ipBit(b, i)]
+	}
+
+	return false
+}
+
+func (t *ipTrie) root(ip netip.Addr) *ipTrieNode {
+	if ip.Is4() {
+		return t.v4
+	}
+
+	return t.v6
+}
+
+func ipBit(b []byte, i int) int {
+	return int(b[i/8]>>(7-uint(i%8))) & 1
+}
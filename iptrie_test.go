@@ -0,0 +1,52 @@
+package gohttpmw
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestIPTrie(t *testing.T) {
+	trie := newIPTrie()
+	for _, cidr := range []string{"10.0.0.0/8", "192.168.1.1", "2001:db8::/32", "::ffff:172.16.0.0/108"} {
+		prefix, err := parsePrefix(cidr)
+		if err != nil {
+			t.Fatalf("parsePrefix(%s) failed %v", cidr, err)
+		}
+		trie.insert(prefix)
+	}
+
+	tests := []struct {
+		ip       string
+		expected bool
+	}{
+		{ip: "10.1.2.3", expected: true},
+		{ip: "11.0.0.1", expected: false},
+		{ip: "192.168.1.1", expected: true},
+		{ip: "192.168.1.2", expected: false},
+		{ip: "::ffff:10.0.0.1", expected: true},
+		{ip: "172.16.5.5", expected: true},
+		{ip: "2001:db8:1::1", expected: true},
+		{ip: "2001:db9::1", expected: false},
+		{ip: "::1", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := trie.contains(netip.MustParseAddr(tt.ip)); got != tt.expected {
+				t.Errorf("expected %t, got %t", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestIPTrieAll(t *testing.T) {
+	trie := newIPTrie()
+	trie.insert(netip.MustParsePrefix("0.0.0.0/0"))
+
+	if !trie.contains(netip.MustParseAddr("1.2.3.4")) {
+		t.Errorf("expected 0.0.0.0/0 to contain all ipv4")
+	}
+	if trie.contains(netip.MustParseAddr("::2")) {
+		t.Errorf("expected 0.0.0.0/0 not to contain ipv6")
+	}
+}
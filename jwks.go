@@ -0,0 +1,271 @@
+package gohttpmw
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrJWKSKeyNotFound is returned when no key of the set matches the token
+var ErrJWKSKeyNotFound = errors.New("jwks: key not found")
+
+// JWKS is a JWTKeySource backed by a JSON Web Key Set,
+// either loaded from a file or fetched from a url and cached
+type JWKS struct {
+	// mu guards the fields below, it is never held during a fetch
+	mu          sync.RWMutex
+	keys        map[string]interface{}
+	url         string
+	client      *http.Client
+	ttl         time.Duration
+	minRefresh  time.Duration
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	// inflight is the running fetch, shared by the concurrent refreshes
+	inflight *jwksFetch
+}
+
+type jwksFetch struct {
+	done chan struct{}
+	err  error
+}
+
+// NewJWKSFromFile loads a JSON Web Key Set from the named file
+func NewJWKSFromFile(name string) (*JWKS, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	keys, err := parseJWKS(f)
+	if err != nil {
+		return nil, err
+	}
+
+	return &JWKS{keys: keys}, nil
+}
+
+// NewJWKSFromURL creates a JSON Web Key Set fetched from url,
+// the keys are cached for ttl and refetched earlier if a token
+// refers to an unknown key id
+func NewJWKSFromURL(url string, ttl time.Duration) *JWKS {
+	return &JWKS{
+		url:        url,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		ttl:        ttl,
+		minRefresh: time.Second,
+	}
+}
+
+// Key returns the key with the key id kid, if kid is empty
+// the set must contain a single key. Once the ttl is over the cached
+// keys are still used while they are refetched in the background
+func (j *JWKS) Key(kid string) (interface{}, error) {
+	j.mu.RLock()
+	stale := j.url != "" && time.Since(j.fetchedAt) > j.ttl
+	key, ok := j.lookup(kid)
+	j.mu.RUnlock()
+
+	var fetchErr error
+	switch {
+	case ok && stale:
+		go func() { _ = j.refresh() }()
+		return key, nil
+	case ok:
+		return key, nil
+	case stale:
+		fetchErr = j.refresh()
+		if key, ok := j.rlookup(kid); ok {
+			return key, nil
+		}
+	}
+
+	// The keys may have been rotated
+	if j.url != "" {
+		if err := j.refresh(); err != nil {
+			fetchErr = err
+		}
+		if key, ok := j.rlookup(kid); ok {
+			return key, nil
+		}
+	}
+
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+
+	return nil, ErrJWKSKeyNotFound
+}
+
+func (j *JWKS) lookup(kid string) (interface{}, bool) {
+	if kid == "" && len(j.keys) == 1 {
+		for _, key := range j.keys {
+			return key, true
+		}
+	}
+	key, ok := j.keys[kid]
+
+	return key, ok
+}
+
+func (j *JWKS) rlookup(kid string) (interface{}, bool) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	return j.lookup(kid)
+}
+
+// refresh fetches the keys, not more often than minRefresh, the
+// concurrent refreshes wait for the same fetch. The previous keys
+// are kept if it fails
+func (j *JWKS) refresh() error {
+	j.mu.Lock()
+	if f := j.inflight; f != nil {
+		j.mu.Unlock()
+		<-f.done
+		return f.err
+	}
+	if time.Since(j.attemptedAt) < j.minRefresh {
+		j.mu.Unlock()
+		return nil
+	}
+	f := &jwksFetch{done: make(chan struct{})}
+	j.inflight = f
+	j.attemptedAt = time.Now()
+	j.mu.Unlock()
+
+	keys, err := j.fetch()
+
+	j.mu.Lock()
+	if err == nil {
+		j.keys = keys
+		j.fetchedAt = time.Now()
+	}
+	j.inflight = nil
+	j.mu.Unlock()
+
+	f.err = err
+	close(f.done)
+
+	return err
+}
+
+func (j *JWKS) fetch() (map[string]interface{}, error) {
+	resp, err := j.client.Get(j.url)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf(
+			"jwks: fetching %s: unexpected status %d", j.url, resp.StatusCode,
+		)
+	}
+
+	return parseJWKS(resp.Body)
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+// parseJWKS decodes a JSON Web Key Set, keys not meant for signatures
+// and unsupported key types are skipped
+func parseJWKS(r io.Reader) (map[string]interface{}, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(r).Decode(&set); err != nil {
+		return nil, fmt.Errorf("jwks: %v", err)
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("jwks: key %q: %v", k.Kid, err)
+		}
+		if key != nil {
+			keys[k.Kid] = key
+		}
+	}
+
+	return keys, nil
+}
+
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeJWKInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeJWKInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, nil
+		}
+		x, err := decodeJWKInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeJWKInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, nil
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	case "oct":
+		return base64.RawURLEncoding.DecodeString(k.K)
+	}
+
+	return nil, nil
+}
+
+func decodeJWKInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
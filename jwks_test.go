@@ -0,0 +1,234 @@
+package gohttpmw
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewJWKSFromFile(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edPub, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	set := testJWKS(map[string]interface{}{
+		"rsa": &rsaKey.PublicKey,
+		"ec":  &ecKey.PublicKey,
+		"ed":  edPub,
+	})
+	name := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(name, set, 0600); err != nil {
+		t.Fatalf("writing jwks failed %v", err)
+	}
+
+	jwks, err := NewJWKSFromFile(name)
+	if err != nil {
+		t.Fatalf("NewJWKSFromFile failed %v", err)
+	}
+
+	claims := map[string]interface{}{
+		"sub": "pollux",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}
+	tests := []struct {
+		name           string
+		token          string
+		expectedStatus int
+	}{
+		{
+			name:           "RS256 key",
+			token:          signTestJWTWithKid(t, "RS256", "rsa", rsaKey, claims),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "ES256 key",
+			token:          signTestJWTWithKid(t, "ES256", "ec", ecKey, claims),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "EdDSA key",
+			token:          signTestJWTWithKid(t, "EdDSA", "ed", edKey, claims),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "key id mismatch",
+			token:          signTestJWTWithKid(t, "EdDSA", "rsa", edKey, claims),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "unknown key id",
+			token:          signTestJWTWithKid(t, "RS256", "other", rsaKey, claims),
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "no key id with several keys",
+			token:          signTestJWT(t, "RS256", rsaKey, claims),
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeHandler := http.HandlerFunc(
+				func(w http.ResponseWriter, req *http.Request) {},
+			)
+			midWared := JWTAuth(jwks)(fakeHandler)
+			rr := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, `/`, nil)
+			request.Header.Set("Authorization", "Bearer "+tt.token)
+			midWared.ServeHTTP(rr, request)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+		})
+	}
+}
+
+func TestNewJWKSFromURL(t *testing.T) {
+	oldKey, _, _ := ed25519.GenerateKey(rand.Reader)
+	newKey, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	var fetches int32
+	rotated := int32(0)
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			atomic.AddInt32(&fetches, 1)
+			keys := map[string]interface{}{"old": oldKey}
+			if atomic.LoadInt32(&rotated) == 1 {
+				keys = map[string]interface{}{"new": newKey}
+			}
+			_, _ = w.Write(testJWKS(keys))
+		},
+	))
+	defer srv.Close()
+
+	jwks := NewJWKSFromURL(srv.URL, time.Hour)
+	jwks.minRefresh = 0
+
+	if _, err := jwks.Key("old"); err != nil {
+		t.Fatalf("expected old key, got %v", err)
+	}
+	if _, err := jwks.Key("old"); err != nil {
+		t.Fatalf("expected old key, got %v", err)
+	}
+	if f := atomic.LoadInt32(&fetches); f != 1 {
+		t.Errorf("expected keys to be cached, got %d fetches", f)
+	}
+
+	atomic.StoreInt32(&rotated, 1)
+	if _, err := jwks.Key("new"); err != nil {
+		t.Fatalf("expected rotated key, got %v", err)
+	}
+	if _, err := jwks.Key("old"); err != ErrJWKSKeyNotFound {
+		t.Errorf("expected %v, got %v", ErrJWKSKeyNotFound, err)
+	}
+
+	srv.Close()
+	jwks.fetchedAt = time.Time{}
+	if _, err := jwks.Key("new"); err != nil {
+		t.Errorf("expected stale key to be kept on fetch error, got %v", err)
+	}
+}
+
+func TestJWKSSlowRefresh(t *testing.T) {
+	key, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	var fetches int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			if atomic.AddInt32(&fetches, 1) > 1 {
+				<-release
+			}
+			_, _ = w.Write(testJWKS(map[string]interface{}{"known": key}))
+		},
+	))
+	defer srv.Close()
+	defer close(release)
+
+	jwks := NewJWKSFromURL(srv.URL, time.Hour)
+	jwks.minRefresh = 0
+	if _, err := jwks.Key("known"); err != nil {
+		t.Fatalf("expected known key, got %v", err)
+	}
+
+	// Unknown key ids wait for a single refetch
+	jwks.minRefresh = time.Hour
+	jwks.attemptedAt = time.Time{}
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = jwks.Key("random")
+		}()
+	}
+	for atomic.LoadInt32(&fetches) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// while the known keys are served without waiting
+	done := make(chan error)
+	go func() {
+		_, err := jwks.Key("known")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected known key, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected the cached key not to wait for the refetch")
+	}
+
+	release <- struct{}{}
+	wg.Wait()
+	if f := atomic.LoadInt32(&fetches); f != 2 {
+		t.Errorf("expected the refetches to be shared, got %d fetches", f)
+	}
+}
+
+// testJWKS encodes public keys as a JSON Web Key Set
+func testJWKS(keys map[string]interface{}) []byte {
+	b64 := base64.RawURLEncoding.EncodeToString
+	set := struct {
+		Keys []map[string]string `json:"keys"`
+	}{}
+	for kid, key := range keys {
+		switch k := key.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, map[string]string{
+				"kty": "RSA", "kid": kid,
+				"n": b64(k.N.Bytes()),
+				"e": b64(big.NewInt(int64(k.E)).Bytes()),
+			})
+		case *ecdsa.PublicKey:
+			set.Keys = append(set.Keys, map[string]string{
+				"kty": "EC", "kid": kid, "crv": "P-256",
+				"x": b64(k.X.Bytes()), "y": b64(k.Y.Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, map[string]string{
+				"kty": "OKP", "kid": kid, "crv": "Ed25519", "x": b64(k),
+			})
+		}
+	}
+	b, _ := json.Marshal(set)
+
+	return b
+}
@@ -0,0 +1,411 @@
+package gohttpmw
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// ContextKeyJWTClaims allow storage of the validated jwt claims
+	// in the context
+	ContextKeyJWTClaims = ContextKey("jwtClaims")
+)
+
+// Errors returned when a bearer token is rejected
+var (
+	ErrJWTMissing          = errors.New("jwt: missing bearer token")
+	ErrJWTMalformed        = errors.New("jwt: malformed token")
+	ErrJWTUnsupportedAlg   = errors.New("jwt: unsupported algorithm")
+	ErrJWTInvalidSignature = errors.New("jwt: invalid signature")
+	ErrJWTExpired          = errors.New("jwt: token is expired")
+	ErrJWTNotYetValid      = errors.New("jwt: token is not valid yet")
+	ErrJWTInvalidIssuer    = errors.New("jwt: invalid issuer")
+	ErrJWTInvalidAudience  = errors.New("jwt: invalid audience")
+)
+
+// JWTClaims are the validated claims of a bearer token
+type JWTClaims struct {
+	Issuer    string
+	Subject   string
+	Audience  []string
+	ExpiresAt time.Time
+	NotBefore time.Time
+	IssuedAt  time.Time
+	ID        string
+	// Raw holds all the claims of the token, including the registered ones
+	Raw map[string]interface{}
+}
+
+// JWTKeySource gives the key used to verify a token signed with
+// the key id kid, kid can be empty if the token doesn't specify it.
+// HS256 needs a []byte, RS256 a *rsa.PublicKey, ES256 a P-256
+// *ecdsa.PublicKey and EdDSA an ed25519.PublicKey
+type JWTKeySource interface {
+	Key(kid string) (interface{}, error)
+}
+
+// JWTKeyFunc allows the use of an ordinary function as a JWTKeySource
+type JWTKeyFunc func(kid string) (interface{}, error)
+
+// Key calls f(kid)
+func (f JWTKeyFunc) Key(kid string) (interface{}, error) {
+	return f(kid)
+}
+
+// StaticKey is a JWTKeySource always returning the same key
+func StaticKey(key interface{}) JWTKeySource {
+	return JWTKeyFunc(func(string) (interface{}, error) {
+		return key, nil
+	})
+}
+
+// JWTOption configures the JWTAuth middleware
+type JWTOption func(*jwtConfig)
+
+type jwtConfig struct {
+	issuer     string
+	audience   string
+	leeway     time.Duration
+	realm      string
+	rolesClaim string
+	now        func() time.Time
+}
+
+// WithIssuer rejects tokens not issued by iss
+func WithIssuer(iss string) JWTOption {
+	return func(c *jwtConfig) {
+		c.issuer = iss
+	}
+}
+
+// WithAudience rejects tokens not intended for aud
+func WithAudience(aud string) JWTOption {
+	return func(c *jwtConfig) {
+		c.audience = aud
+	}
+}
+
+// WithLeeway allows for clock skew when checking exp and nbf
+func WithLeeway(d time.Duration) JWTOption {
+	return func(c *jwtConfig) {
+		c.leeway = d
+	}
+}
+
+// WithRealm sets the realm of the WWW-Authenticate challenge
+func WithRealm(realm string) JWTOption {
+	return func(c *jwtConfig) {
+		c.realm = realm
+	}
+}
+
+// WithRolesClaim sets the claim holding the principal's roles,
+// roles by default
+func WithRolesClaim(claim string) JWTOption {
+	return func(c *jwtConfig) {
+		c.rolesClaim = claim
+	}
+}
+
+// JWTAuth validates the bearer token of the request and puts its claims
+// and the matching principal in the context,
+// requests without a valid token get a 401
+func JWTAuth(keys JWTKeySource, opts ...JWTOption) func(http.Handler) http.Handler {
+	cfg := jwtConfig{rolesClaim: "roles", now: time.Now}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := bearerToken(r)
+			if !ok {
+				SetRequestError(r, ErrJWTMissing)
+				w.Header().Set("WWW-Authenticate", cfg.challenge(""))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			claims, err := cfg.parse(token, keys)
+			if err != nil {
+				SetRequestError(r, err)
+				w.Header().Set("WWW-Authenticate", cfg.challenge("invalid_token"))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), ContextKeyJWTClaims, claims)
+			ctx = WithPrincipal(ctx, cfg.principal(claims))
+			// We don't want to lose the reference to the Request
+			*r = *r.WithContext(ctx)
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// GetJWTClaims will retrieve the validated jwt claims
+// from the context if there are some
+func GetJWTClaims(ctx context.Context) *JWTClaims {
+	if claims, ok := ctx.Value(ContextKeyJWTClaims).(*JWTClaims); ok {
+		return claims
+	}
+
+	return nil
+}
+
+// JWTSubject is a role extractor for RBAC using the sub claim
+func JWTSubject(ctx context.Context) string {
+	if claims := GetJWTClaims(ctx); claims != nil {
+		return claims.Subject
+	}
+
+	return ""
+}
+
+// JWTClaimRole returns a role extractor for RBAC using the named claim,
+// if the claim is a list its first element is used
+func JWTClaimRole(claim string) func(context.Context) string {
+	return func(ctx context.Context) string {
+		claims := GetJWTClaims(ctx)
+		if claims == nil {
+			return ""
+		}
+
+		if values := claimStrings(claims.Raw[claim]); len(values) > 0 {
+			return values[0]
+		}
+
+		return ""
+	}
+}
+
+func (c *jwtConfig) principal(claims *JWTClaims) *Principal {
+	p := &Principal{
+		ID:         claims.Subject,
+		Roles:      claimStrings(claims.Raw[c.rolesClaim]),
+		AuthMethod: AuthMethodJWT,
+		Attributes: claims.Raw,
+	}
+
+	// OAuth2 uses a space separated scope, some providers a scp list
+	if scope, ok := claims.Raw["scope"].(string); ok {
+		p.Scopes = strings.Fields(scope)
+	} else {
+		p.Scopes = claimStrings(claims.Raw["scp"])
+	}
+
+	return p
+}
+
+// claimStrings returns a claim holding a string or a list of strings
+func claimStrings(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+
+	return nil
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	auth := r.Header.Get("Authorization")
+	if len(auth) < 7 || !strings.EqualFold(auth[:7], "bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(auth[7:])
+
+	return token, token != ""
+}
+
+func (c *jwtConfig) challenge(errCode string) string {
+	params := []string{}
+	if c.realm != "" {
+		params = append(params, fmt.Sprintf("realm=%q", c.realm))
+	}
+	if errCode != "" {
+		params = append(params, fmt.Sprintf("error=%q", errCode))
+	}
+	if len(params) == 0 {
+		return "Bearer"
+	}
+
+	return "Bearer " + strings.Join(params, ", ")
+}
+
+// parse verifies the signature of the token and validates its claims
+func (c *jwtConfig) parse(token string, keys JWTKeySource) (*JWTClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrJWTMalformed
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrJWTMalformed
+	}
+
+	key, err := keys.Key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verifyJWTSignature(
+		header.Alg, key, parts[0]+"."+parts[1], sig,
+	); err != nil {
+		return nil, err
+	}
+
+	raw := make(map[string]interface{})
+	if err := decodeJWTSegment(parts[1], &raw); err != nil {
+		return nil, err
+	}
+
+	claims := newJWTClaims(raw)
+	if err := c.validate(claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func (c *jwtConfig) validate(claims *JWTClaims) error {
+	now := c.now()
+
+	if claims.ExpiresAt.IsZero() || !now.Before(claims.ExpiresAt.Add(c.leeway)) {
+		return ErrJWTExpired
+	}
+	if !claims.NotBefore.IsZero() && now.Add(c.leeway).Before(claims.NotBefore) {
+		return ErrJWTNotYetValid
+	}
+	if c.issuer != "" && claims.Issuer != c.issuer {
+		return ErrJWTInvalidIssuer
+	}
+	if c.audience != "" {
+		for _, aud := range claims.Audience {
+			if aud == c.audience {
+				return nil
+			}
+		}
+		return ErrJWTInvalidAudience
+	}
+
+	return nil
+}
+
+func decodeJWTSegment(seg string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return ErrJWTMalformed
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return ErrJWTMalformed
+	}
+
+	return nil
+}
+
+// verifyJWTSignature checks that alg matches the type of key,
+// so that a public key can never be used as an hmac secret
+func verifyJWTSignature(alg string, key interface{}, signed string, sig []byte) error {
+	hash := sha256.Sum256([]byte(signed))
+
+	switch alg {
+	case "HS256":
+		secret, ok := key.([]byte)
+		if !ok {
+			return ErrJWTUnsupportedAlg
+		}
+		mac := hmac.New(sha256.New, secret)
+		_, _ = mac.Write([]byte(signed))
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return ErrJWTInvalidSignature
+		}
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return ErrJWTUnsupportedAlg
+		}
+		if rsa.VerifyPKCS1v15(pub, crypto.SHA256, hash[:], sig) != nil {
+			return ErrJWTInvalidSignature
+		}
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || pub.Curve != elliptic.P256() {
+			return ErrJWTUnsupportedAlg
+		}
+		if len(sig) != 64 {
+			return ErrJWTInvalidSignature
+		}
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(pub, hash[:], r, s) {
+			return ErrJWTInvalidSignature
+		}
+	case "EdDSA":
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return ErrJWTUnsupportedAlg
+		}
+		if !ed25519.Verify(pub, []byte(signed), sig) {
+			return ErrJWTInvalidSignature
+		}
+	default:
+		return ErrJWTUnsupportedAlg
+	}
+
+	return nil
+}
+
+func newJWTClaims(raw map[string]interface{}) *JWTClaims {
+	claims := &JWTClaims{Raw: raw}
+	claims.Issuer, _ = raw["iss"].(string)
+	claims.Subject, _ = raw["sub"].(string)
+	claims.ID, _ = raw["jti"].(string)
+	claims.ExpiresAt = numericDate(raw["exp"])
+	claims.NotBefore = numericDate(raw["nbf"])
+	claims.IssuedAt = numericDate(raw["iat"])
+
+	claims.Audience = claimStrings(raw["aud"])
+
+	return claims
+}
+
+func numericDate(v interface{}) time.Time {
+	f, ok := v.(float64)
+	if !ok {
+		return time.Time{}
+	}
+	sec := int64(f)
+
+	return time.Unix(sec, int64((f-float64(sec))*float64(time.Second)))
+}
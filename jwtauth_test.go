@@ -0,0 +1,313 @@
+package gohttpmw
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJWTAuth(t *testing.T) {
+	secret := []byte("secret")
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edPub, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	valid := map[string]interface{}{
+		"sub":   "pollux",
+		"iss":   "https://issuer.test",
+		"aud":   []string{"api", "other"},
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"roles": []string{"admin"},
+		"scope": "read write",
+	}
+	with := func(k string, v interface{}) map[string]interface{} {
+		c := map[string]interface{}{}
+		for kk, vv := range valid {
+			c[kk] = vv
+		}
+		c[k] = v
+		return c
+	}
+
+	tests := []struct {
+		name           string
+		keys           JWTKeySource
+		authorization  string
+		expectedStatus int
+		expectedErr    error
+	}{
+		{
+			name:           "HS256 valid token",
+			keys:           StaticKey(secret),
+			authorization:  "Bearer " + signTestJWT(t, "HS256", secret, valid),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "RS256 valid token",
+			keys:           StaticKey(&rsaKey.PublicKey),
+			authorization:  "Bearer " + signTestJWT(t, "RS256", rsaKey, valid),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "ES256 valid token",
+			keys:           StaticKey(&ecKey.PublicKey),
+			authorization:  "bearer " + signTestJWT(t, "ES256", ecKey, valid),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "EdDSA valid token",
+			keys:           StaticKey(edPub),
+			authorization:  "Bearer " + signTestJWT(t, "EdDSA", edKey, valid),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "missing token",
+			keys:           StaticKey(secret),
+			expectedStatus: http.StatusUnauthorized,
+			expectedErr:    ErrJWTMissing,
+		},
+		{
+			name:           "malformed token",
+			keys:           StaticKey(secret),
+			authorization:  "Bearer not.a.token",
+			expectedStatus: http.StatusUnauthorized,
+			expectedErr:    ErrJWTMalformed,
+		},
+		{
+			name:           "wrong secret",
+			keys:           StaticKey([]byte("other")),
+			authorization:  "Bearer " + signTestJWT(t, "HS256", secret, valid),
+			expectedStatus: http.StatusUnauthorized,
+			expectedErr:    ErrJWTInvalidSignature,
+		},
+		{
+			name:           "alg none rejected",
+			keys:           StaticKey(secret),
+			authorization:  "Bearer " + signTestJWT(t, "none", nil, valid),
+			expectedStatus: http.StatusUnauthorized,
+			expectedErr:    ErrJWTUnsupportedAlg,
+		},
+		{
+			name:           "HS256 with a public key rejected",
+			keys:           StaticKey(&rsaKey.PublicKey),
+			authorization:  "Bearer " + signTestJWT(t, "HS256", secret, valid),
+			expectedStatus: http.StatusUnauthorized,
+			expectedErr:    ErrJWTUnsupportedAlg,
+		},
+		{
+			name: "expired token",
+			keys: StaticKey(secret),
+			authorization: "Bearer " + signTestJWT(t, "HS256", secret,
+				with("exp", time.Now().Add(-time.Minute).Unix())),
+			expectedStatus: http.StatusUnauthorized,
+			expectedErr:    ErrJWTExpired,
+		},
+		{
+			name: "expired token within leeway",
+			keys: StaticKey(secret),
+			authorization: "Bearer " + signTestJWT(t, "HS256", secret,
+				with("exp", time.Now().Add(-10*time.Second).Unix())),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "token without exp",
+			keys: StaticKey(secret),
+			authorization: "Bearer " + signTestJWT(t, "HS256", secret,
+				with("exp", nil)),
+			expectedStatus: http.StatusUnauthorized,
+			expectedErr:    ErrJWTExpired,
+		},
+		{
+			name: "token not valid yet",
+			keys: StaticKey(secret),
+			authorization: "Bearer " + signTestJWT(t, "HS256", secret,
+				with("nbf", time.Now().Add(time.Minute).Unix())),
+			expectedStatus: http.StatusUnauthorized,
+			expectedErr:    ErrJWTNotYetValid,
+		},
+		{
+			name: "wrong issuer",
+			keys: StaticKey(secret),
+			authorization: "Bearer " + signTestJWT(t, "HS256", secret,
+				with("iss", "https://evil.test")),
+			expectedStatus: http.StatusUnauthorized,
+			expectedErr:    ErrJWTInvalidIssuer,
+		},
+		{
+			name: "wrong audience",
+			keys: StaticKey(secret),
+			authorization: "Bearer " + signTestJWT(t, "HS256", secret,
+				with("aud", "other")),
+			expectedStatus: http.StatusUnauthorized,
+			expectedErr:    ErrJWTInvalidAudience,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var claims *JWTClaims
+			var principal *Principal
+			fakeHandler := http.HandlerFunc(
+				func(w http.ResponseWriter, req *http.Request) {
+					claims = GetJWTClaims(req.Context())
+					principal = GetPrincipal(req.Context())
+				},
+			)
+			midWared := JWTAuth(
+				tt.keys,
+				WithIssuer("https://issuer.test"),
+				WithAudience("api"),
+				WithLeeway(30*time.Second),
+				WithRealm("test"),
+			)(fakeHandler)
+
+			rr := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, `/`, nil)
+			if tt.authorization != "" {
+				request.Header.Set("Authorization", tt.authorization)
+			}
+			midWared.ServeHTTP(rr, request)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if err := GetRequestError(request.Context()); err != tt.expectedErr {
+				t.Errorf("expected request error %v, got %v", tt.expectedErr, err)
+			}
+
+			if tt.expectedStatus != http.StatusOK {
+				if !strings.HasPrefix(
+					rr.Header().Get("WWW-Authenticate"), `Bearer realm="test"`,
+				) {
+					t.Errorf(
+						"expected a bearer challenge, got %q",
+						rr.Header().Get("WWW-Authenticate"),
+					)
+				}
+				return
+			}
+
+			if claims == nil || claims.Subject != "pollux" {
+				t.Fatalf("expected claims in context, got %+v", claims)
+			}
+			if claims.Issuer != "https://issuer.test" ||
+				len(claims.Audience) != 2 || claims.ExpiresAt.IsZero() {
+				t.Errorf("registered claims not parsed, got %+v", claims)
+			}
+			if principal == nil || principal.ID != "pollux" ||
+				principal.AuthMethod != AuthMethodJWT ||
+				!reflect.DeepEqual(principal.Roles, []string{"admin"}) ||
+				!reflect.DeepEqual(principal.Scopes, []string{"read", "write"}) {
+				t.Errorf("expected principal in context, got %+v", principal)
+			}
+		})
+	}
+}
+
+func TestJWTRoleExtractors(t *testing.T) {
+	ctx := context.Background()
+	if role := JWTSubject(ctx); role != "" {
+		t.Errorf("expected no role, got %s", role)
+	}
+	if role := JWTClaimRole("roles")(ctx); role != "" {
+		t.Errorf("expected no role, got %s", role)
+	}
+
+	ctx = context.WithValue(ctx, ContextKeyJWTClaims, &JWTClaims{
+		Subject: "pollux",
+		Raw: map[string]interface{}{
+			"role":  "admin",
+			"roles": []interface{}{"editor", "viewer"},
+		},
+	})
+
+	tests := []struct {
+		name    string
+		extract func(context.Context) string
+		want    string
+	}{
+		{name: "subject", extract: JWTSubject, want: "pollux"},
+		{name: "string claim", extract: JWTClaimRole("role"), want: "admin"},
+		{name: "list claim", extract: JWTClaimRole("roles"), want: "editor"},
+		{name: "missing claim", extract: JWTClaimRole("group"), want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.extract(ctx); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+// signTestJWT creates a compact jwt, key is the signing key for alg
+func signTestJWT(
+	t *testing.T,
+	alg string,
+	key interface{},
+	claims map[string]interface{},
+) string {
+	t.Helper()
+
+	return signTestJWTWithKid(t, alg, "", key, claims)
+}
+
+func signTestJWTWithKid(
+	t *testing.T,
+	alg string,
+	kid string,
+	key interface{},
+	claims map[string]interface{},
+) string {
+	t.Helper()
+
+	h := map[string]string{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		h["kid"] = kid
+	}
+	header, _ := json.Marshal(h)
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." +
+		base64.RawURLEncoding.EncodeToString(payload)
+	hash := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	var err error
+	switch alg {
+	case "HS256":
+		mac := hmac.New(sha256.New, key.([]byte))
+		_, _ = mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case "RS256":
+		sig, err = rsa.SignPKCS1v15(
+			rand.Reader, key.(*rsa.PrivateKey), crypto.SHA256, hash[:],
+		)
+	case "ES256":
+		r, s, errS := ecdsa.Sign(rand.Reader, key.(*ecdsa.PrivateKey), hash[:])
+		err = errS
+		sig = make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+	case "EdDSA":
+		sig = ed25519.Sign(key.(ed25519.PrivateKey), []byte(signed))
+	}
+	if err != nil {
+		t.Fatalf("signing test jwt failed %v", err)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
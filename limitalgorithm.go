@@ -0,0 +1,117 @@
+package gohttpmw
+
+import (
+	"math"
+	"time"
+)
+
+// LimitAlgorithm adjusts the limit of a ConcurrencyLimiter each time
+// a request completes, it is called with the limiter locked.
+// didDrop tells if the request failed because of overload
+type LimitAlgorithm interface {
+	Update(
+		limit float64,
+		latency time.Duration,
+		inFlight int,
+		didDrop bool,
+	) float64
+}
+
+// FixedLimit never changes the limit
+type FixedLimit struct{}
+
+// Update returns limit
+func (FixedLimit) Update(limit float64, _ time.Duration, _ int, _ bool) float64 {
+	return limit
+}
+
+// AIMDLimit increases the limit by one while requests are fast
+// and multiplies it by Backoff when one is dropped or slower than Threshold
+type AIMDLimit struct {
+	Min int
+	Max int
+	// Backoff is 0.9 by default
+	Backoff   float64
+	Threshold time.Duration
+}
+
+// Update applies an additive increase or a multiplicative decrease
+func (a AIMDLimit) Update(
+	limit float64,
+	latency time.Duration,
+	inFlight int,
+	didDrop bool,
+) float64 {
+	backoff := a.Backoff
+	if backoff == 0 {
+		backoff = 0.9
+	}
+
+	switch {
+	case didDrop || (a.Threshold > 0 && latency > a.Threshold):
+		limit *= backoff
+	case float64(inFlight)*2 >= limit:
+		// Only grow when the current limit is actually used
+		limit++
+	}
+
+	return clampLimit(limit, a.Min, a.Max)
+}
+
+// GradientLimit adjusts the limit from the ratio between the long term
+// average latency and the latest one, so it shrinks as soon as
+// latencies rise and grows back when they recover
+type GradientLimit struct {
+	Min int
+	Max int
+	// Tolerance is how much the latency may grow above the long term average
+	// before the limit shrinks, 2 by default
+	Tolerance float64
+	// Smoothing is the weight of a new limit, 0.2 by default
+	Smoothing float64
+
+	longLatency float64
+}
+
+// Update moves the limit towards limit × gradient + queue allowance
+func (g *GradientLimit) Update(
+	limit float64,
+	latency time.Duration,
+	_ int,
+	_ bool,
+) float64 {
+	tolerance, smoothing := g.Tolerance, g.Smoothing
+	if tolerance == 0 {
+		tolerance = 2
+	}
+	if smoothing == 0 {
+		smoothing = 0.2
+	}
+
+	short := float64(latency)
+	if short <= 0 {
+		return limit
+	}
+	if g.longLatency == 0 {
+		g.longLatency = short
+	}
+	// Exponential moving average over roughly 500 requests, 1/0.002
+	g.longLatency = g.longLatency*0.998 + short*0.002
+
+	gradient := math.Max(0.5, math.Min(1, tolerance*g.longLatency/short))
+	newLimit := limit*gradient + math.Sqrt(limit)
+	limit = limit*(1-smoothing) + newLimit*smoothing
+
+	return clampLimit(limit, g.Min, g.Max)
+}
+
+func clampLimit(limit float64, min, max int) float64 {
+	if min > 0 && limit < float64(min) {
+		return float64(min)
+	}
+	if max > 0 && limit > float64(max) {
+		return float64(max)
+	}
+
+	return limit
+}
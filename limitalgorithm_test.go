@@ -0,0 +1,81 @@
+package gohttpmw
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFixedLimit(t *testing.T) {
+	if l := (FixedLimit{}).Update(10, time.Hour, 10, true); l != 10 {
+		t.Errorf("expected limit to stay 10, got %f", l)
+	}
+}
+
+func TestAIMDLimit(t *testing.T) {
+	a := AIMDLimit{Min: 2, Max: 12, Backoff: 0.5, Threshold: time.Second}
+
+	tests := []struct {
+		name     string
+		limit    float64
+		latency  time.Duration
+		inFlight int
+		didDrop  bool
+		want     float64
+	}{
+		{
+			name: "increase when used", limit: 10,
+			latency: time.Millisecond, inFlight: 5, want: 11,
+		},
+		{
+			name: "stay when unused", limit: 10,
+			latency: time.Millisecond, inFlight: 2, want: 10,
+		},
+		{
+			name: "capped at max", limit: 12,
+			latency: time.Millisecond, inFlight: 12, want: 12,
+		},
+		{
+			name: "decrease on drop", limit: 10,
+			latency: time.Millisecond, didDrop: true, want: 5,
+		},
+		{
+			name: "decrease when slow", limit: 10,
+			latency: 2 * time.Second, want: 5,
+		},
+		{
+			name: "floored at min", limit: 3,
+			latency: 2 * time.Second, want: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := a.Update(tt.limit, tt.latency, tt.inFlight, tt.didDrop)
+			if got != tt.want {
+				t.Errorf("AIMDLimit.Update() = %f, want %f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGradientLimit(t *testing.T) {
+	g := &GradientLimit{Min: 1, Max: 100}
+
+	limit := 20.0
+	for i := 0; i < 100; i++ {
+		limit = g.Update(limit, 10*time.Millisecond, 10, false)
+	}
+	steady := limit
+	if steady <= 20 {
+		t.Errorf("expected the limit to grow with steady latencies, got %f", steady)
+	}
+
+	for i := 0; i < 20; i++ {
+		limit = g.Update(limit, 100*time.Millisecond, 10, false)
+	}
+	if limit >= steady {
+		t.Errorf("expected the limit to shrink when latency rises, got %f", limit)
+	}
+	if limit < 1 {
+		t.Errorf("expected the limit to stay above min, got %f", limit)
+	}
+}
@@ -0,0 +1,105 @@
+package gohttpmw
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/rs/zerolog"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// ContextKeyLog allow storage of the request logger in the context
+	ContextKeyLog = ContextKey("log")
+)
+
+// requestLogger is the logger of a request, for slog and for the
+// logger of the access log when it is logrus or zerolog
+type requestLogger struct {
+	slog   *slog.Logger
+	logrus *logrus.Entry
+	zero   *zerolog.Logger
+}
+
+// with returns a copy of rl with the field k added to all the loggers
+func (rl *requestLogger) with(k, v string) *requestLogger {
+	c := &requestLogger{slog: rl.slog.With(k, v)}
+	if rl.logrus != nil {
+		c.logrus = rl.logrus.WithField(k, v)
+	}
+	if rl.zero != nil {
+		z := rl.zero.With().Str(k, v).Logger()
+		c.zero = &z
+	}
+
+	return c
+}
+
+// requestLogFields are the fields of the request logger, shared with
+// the access log so that the logs of a request can be correlated
+func requestLogFields(r *http.Request) [][2]string {
+	fields := [][2]string{
+		{"http_method", r.Method},
+		{"http_path", r.URL.Path},
+	}
+	if reqID := GetRequestID(r.Context()); reqID != "" {
+		fields = append(fields, [2]string{"request_id", reqID})
+	}
+	if userID := principalID(r.Context()); userID != "" {
+		fields = append(fields, [2]string{"user_id", userID})
+	}
+
+	return fields
+}
+
+// withRequestLogger adds the request logger to the context of r,
+// the user_id is added later on by WithPrincipal
+func withRequestLogger(r *http.Request, rl *requestLogger) {
+	for _, f := range requestLogFields(r) {
+		rl = rl.with(f[0], f[1])
+	}
+	*r = *r.WithContext(context.WithValue(r.Context(), ContextKeyLog, rl))
+}
+
+// withRequestLoggerPrincipal adds the user_id of p to the request logger
+func withRequestLoggerPrincipal(ctx context.Context, p *Principal) context.Context {
+	rl, ok := ctx.Value(ContextKeyLog).(*requestLogger)
+	if !ok || p == nil || p.ID == "" {
+		return ctx
+	}
+
+	return context.WithValue(ctx, ContextKeyLog, rl.with("user_id", p.ID))
+}
+
+// LogFromContext returns the logger of the request, set by Logger,
+// LoggerZero or LoggerSlog with the request id, method, path and
+// principal of the request. It logs to the logger of the access log
+// and is slog.Default() when there is none
+func LogFromContext(ctx context.Context) *slog.Logger {
+	if rl, ok := ctx.Value(ContextKeyLog).(*requestLogger); ok {
+		return rl.slog
+	}
+
+	return slog.Default()
+}
+
+// LogrusFromContext returns the logger of the request set by Logger,
+// or an entry of the logrus standard logger when there is none
+func LogrusFromContext(ctx context.Context) *logrus.Entry {
+	if rl, ok := ctx.Value(ContextKeyLog).(*requestLogger); ok && rl.logrus != nil {
+		return rl.logrus
+	}
+
+	return logrus.NewEntry(logrus.StandardLogger())
+}
+
+// LogZeroFromContext returns the logger of the request set by LoggerZero,
+// or zerolog.Ctx(ctx) when there is none
+func LogZeroFromContext(ctx context.Context) *zerolog.Logger {
+	if rl, ok := ctx.Value(ContextKeyLog).(*requestLogger); ok && rl.zero != nil {
+		return rl.zero
+	}
+
+	return zerolog.Ctx(ctx)
+}
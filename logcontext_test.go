@@ -0,0 +1,120 @@
+package gohttpmw
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/sirupsen/logrus"
+)
+
+func TestLogFromContext(t *testing.T) {
+	tests := []struct {
+		name   string
+		logger func(*bytes.Buffer) func(http.Handler) http.Handler
+	}{
+		{
+			name: "logrus",
+			logger: func(out *bytes.Buffer) func(http.Handler) http.Handler {
+				l := logrus.New()
+				l.Out = out
+				l.SetFormatter(&logrus.JSONFormatter{})
+				return Logger(l)
+			},
+		},
+		{
+			name: "zerolog",
+			logger: func(out *bytes.Buffer) func(http.Handler) http.Handler {
+				return LoggerZero(zerolog.New(out))
+			},
+		},
+		{
+			name: "slog",
+			logger: func(out *bytes.Buffer) func(http.Handler) http.Handler {
+				return LoggerSlog(slog.New(slog.NewJSONHandler(out, nil)))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			auth := func(h http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					*r = *r.WithContext(WithPrincipal(r.Context(), &Principal{ID: "pollux"}))
+					h.ServeHTTP(w, r)
+				})
+			}
+			handler := NewChain(RequestID(), tt.logger(out), auth).ThenFunc(
+				func(w http.ResponseWriter, r *http.Request) {
+					LogFromContext(r.Context()).Info("fishing", "bait", "worm")
+				},
+			)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/fish?name=nemo", nil))
+
+			lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+			if len(lines) != 2 {
+				t.Fatalf("expected the handler log and the access log, got %q", out.String())
+			}
+			var handlerLog, accessLog map[string]interface{}
+			if err := json.Unmarshal([]byte(lines[0]), &handlerLog); err != nil {
+				t.Fatalf("error unmarshalling log %v", err)
+			}
+			if err := json.Unmarshal([]byte(lines[1]), &accessLog); err != nil {
+				t.Fatalf("error unmarshalling log %v", err)
+			}
+
+			expected := map[string]interface{}{
+				"request_id":  rr.Header().Get("requestID"),
+				"user_id":     "pollux",
+				"http_method": http.MethodPost,
+				"http_path":   "/fish",
+				"bait":        "worm",
+			}
+			for k, v := range expected {
+				if handlerLog[k] != v {
+					t.Errorf("expected %s %v, got %v", k, v, handlerLog[k])
+				}
+			}
+			if handlerLog["request_id"] != accessLog["request_id"] {
+				t.Errorf(
+					"expected the request ids to match, got %v and %v",
+					handlerLog["request_id"], accessLog["request_id"],
+				)
+			}
+		})
+	}
+}
+
+func TestLogFromContextNative(t *testing.T) {
+	ctx := context.Background()
+	if LogFromContext(ctx) != slog.Default() {
+		t.Errorf("expected slog.Default() without request logger")
+	}
+	if LogrusFromContext(ctx).Logger != logrus.StandardLogger() {
+		t.Errorf("expected the logrus standard logger without request logger")
+	}
+	if LogZeroFromContext(ctx).GetLevel() != zerolog.Disabled {
+		t.Errorf("expected a disabled zerolog logger without request logger")
+	}
+
+	out := &bytes.Buffer{}
+	handler := NewChain(RequestID(), LoggerZero(zerolog.New(out))).ThenFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			LogZeroFromContext(r.Context()).Info().Msg("native")
+			if LogrusFromContext(r.Context()).Logger != logrus.StandardLogger() {
+				t.Errorf("expected the logrus standard logger with LoggerZero")
+			}
+		},
+	)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if !strings.Contains(out.String(), `"http_path":"/","request_id":`) {
+		t.Errorf("expected the request fields in the native log, got %s", out.String())
+	}
+}
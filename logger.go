@@ -2,14 +2,19 @@ package gohttpmw
 
 import (
 	"context"
+	"errors"
+	"log/slog"
 	"net/http"
+	"sort"
 	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
 // Logger will run the full request details
-// if you need performance, look into loggerZero
+// if you need performance, look into loggerZero.
+// The handlers log with the same logger through LogFromContext
+// or LogrusFromContext
 func Logger(l *logrus.Logger) func(http.Handler) http.Handler {
 	return func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -17,38 +22,21 @@ func Logger(l *logrus.Logger) func(http.Handler) http.Handler {
 			naw := newAugmentedResponseWriter(w)
 			startTime := time.Now()
 
+			e := logrus.NewEntry(l)
+			withRequestLogger(r, &requestLogger{
+				slog:   slog.New(&slogLogrusHandler{e: e}),
+				logrus: e,
+			})
+
 			h.ServeHTTP(naw, r)
 
 			logFields := logrus.Fields{}
-
-			scheme := "http"
-			if r.TLS != nil {
-				scheme = "https"
-			}
-			logFields["http_scheme"] = scheme
-			logFields["http_proto"] = r.Proto
-			logFields["http_method"] = r.Method
-
-			logFields["remote_addr"] = r.RemoteAddr
-			logFields["user_agent"] = r.UserAgent()
-
-			logFields["host"] = r.Host
-			logFields["uri"] = r.RequestURI
-
-			logFields["process_time"] = float64(
-				time.Since(startTime) / time.Millisecond,
-			)
-			logFields["http_status"] = naw.httpStatus
-			logFields["resp_length"] = naw.length
-
-			if reqID := GetRequestID(r.Context()); reqID != "" {
-				logFields["request_id"] = reqID
-			}
-
-			// Get additional logging fields
-			atl := GetAddToRequestLog(r.Context())
-			for k, v := range atl {
-				logFields[k] = v
+			for _, f := range accessLogFields(r, naw, startTime) {
+				if d, ok := f.value.(time.Duration); ok {
+					logFields[f.key] = float64(d / time.Millisecond)
+					continue
+				}
+				logFields[f.key] = f.value
 			}
 
 			reqErr := GetRequestError(r.Context())
@@ -67,6 +55,74 @@ func Logger(l *logrus.Logger) func(http.Handler) http.Handler {
 	}
 }
 
+// logField is a field of the request logs
+type logField struct {
+	key   string
+	value interface{}
+}
+
+// accessLogFields are the fields of the request logs shared by Logger,
+// LoggerZero and LoggerSlog, with the stack of the recovered panics,
+// the fields added by AddToRequestLog last
+func accessLogFields(
+	r *http.Request,
+	naw *augmentedResponseWriter,
+	startTime time.Time,
+) []logField {
+	fields := make([]logField, 0, 20)
+	add := func(k string, v interface{}) {
+		fields = append(fields, logField{key: k, value: v})
+	}
+
+	if reqID := GetRequestID(r.Context()); reqID != "" {
+		add("request_id", reqID)
+	}
+	if userID := principalID(r.Context()); userID != "" {
+		add("user_id", userID)
+	}
+	if traceID, spanID, ok := traceIDs(r.Context()); ok {
+		add("trace_id", traceID)
+		add("span_id", spanID)
+	}
+
+	add("http_scheme", requestScheme(r))
+	add("http_proto", r.Proto)
+	add("http_method", r.Method)
+	add("remote_addr", logRemoteAddr(r))
+	add("user_agent", r.UserAgent())
+	add("host", requestHost(r))
+	add("uri", r.RequestURI)
+	add("process_time", time.Since(startTime))
+	add("http_status", naw.httpStatus)
+	add("resp_length", naw.length)
+
+	if enc, n, ok := getCompressionStats(r.Context()); ok {
+		add("resp_encoding", enc)
+		add("resp_uncompressed_length", n)
+	}
+	if enc, ratio, ok := getDecompressionStats(r.Context()); ok {
+		add("req_encoding", enc)
+		add("req_compression_ratio", ratio)
+	}
+
+	var panicErr *PanicError
+	if errors.As(GetRequestError(r.Context()), &panicErr) {
+		add("stack", string(panicErr.Stack))
+	}
+
+	atl := GetAddToRequestLog(r.Context())
+	keys := make([]string, 0, len(atl))
+	for k := range atl {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		add(k, atl[k])
+	}
+
+	return fields
+}
+
 const (
 	// ContextKeyAddToRequestLog allow storage of additional log fields in the context
 	ContextKeyAddToRequestLog = ContextKey("AddToLog")
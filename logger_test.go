@@ -164,6 +164,7 @@ func TestLoggerData(t *testing.T) {
 		requestID           xid.ID
 		requestErrorMessage string
 		withRequestAdd      bool
+		expectedUserID      string
 		expectedLen         int
 	}{
 		{
@@ -227,6 +228,16 @@ func TestLoggerData(t *testing.T) {
 				}),
 			withRequestAdd: true,
 		},
+		{
+			name: "request log with principal",
+			handler: http.HandlerFunc(
+				func(w http.ResponseWriter, req *http.Request) {
+					*req = *req.WithContext(
+						WithPrincipal(req.Context(), &Principal{ID: "pollux"}),
+					)
+				}),
+			expectedUserID: "pollux",
+		},
 	}
 
 	for _, tt := range tc {
@@ -272,6 +283,14 @@ func TestLoggerData(t *testing.T) {
 				return
 			}
 
+			if tt.expectedUserID != "" &&
+				hook.LastEntry().Data["user_id"] != tt.expectedUserID {
+				t.Errorf(
+					"expected user_id %s, got %v",
+					tt.expectedUserID, hook.LastEntry().Data["user_id"])
+				return
+			}
+
 			if tt.withRequestAdd {
 				if hook.LastEntry().Data["fish"] != "fish" {
 					t.Errorf("expected additional field but none present")
@@ -0,0 +1,39 @@
+package gohttpmw
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// LoggerSlog will log the full request details with slog.
+// The handlers log with the same logger through LogFromContext
+func LoggerSlog(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			startTime := time.Now()
+			naw := newAugmentedResponseWriter(w)
+
+			withRequestLogger(r, &requestLogger{slog: logger})
+
+			h.ServeHTTP(naw, r)
+
+			fields := accessLogFields(r, naw, startTime)
+			attrs := make([]slog.Attr, 0, len(fields))
+			for _, f := range fields {
+				attrs = append(attrs, slog.Any(f.key, f.value))
+			}
+
+			level, msg := slog.LevelInfo, ""
+			if reqErr := GetRequestError(r.Context()); reqErr != nil {
+				level, msg = slog.LevelWarn, reqErr.Error()
+				if naw.httpStatus == http.StatusInternalServerError {
+					level = slog.LevelError
+				}
+			}
+
+			logger.LogAttrs(r.Context(), level, msg, attrs...)
+		})
+	}
+}
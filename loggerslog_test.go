@@ -0,0 +1,164 @@
+package gohttpmw
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestLoggerSlog(t *testing.T) {
+	errTest := errors.New("test error")
+
+	tests := []struct {
+		name               string
+		status             int
+		err                error
+		expectedLevel      string
+		expectedMsg        string
+		expectedHTTPStatus float64
+	}{
+		{
+			name:               "classic request log",
+			status:             http.StatusOK,
+			expectedLevel:      "INFO",
+			expectedHTTPStatus: http.StatusOK,
+		},
+		{
+			name:               "request log with error",
+			status:             http.StatusInternalServerError,
+			err:                errTest,
+			expectedLevel:      "ERROR",
+			expectedMsg:        errTest.Error(),
+			expectedHTTPStatus: http.StatusInternalServerError,
+		},
+		{
+			name:               "request log with error as warning",
+			status:             http.StatusBadRequest,
+			err:                errTest,
+			expectedLevel:      "WARN",
+			expectedMsg:        errTest.Error(),
+			expectedHTTPStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			handler := NewChain(
+				RequestID(),
+				LoggerSlog(slog.New(slog.NewJSONHandler(out, nil))),
+			).ThenFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.err != nil {
+					SetRequestError(r, tt.err)
+				}
+				w.WriteHeader(tt.status)
+			})
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("User-Agent", "test")
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			var logRes map[string]interface{}
+			if err := json.Unmarshal(out.Bytes(), &logRes); err != nil {
+				t.Fatalf("error unmarshalling log %v", err)
+			}
+			if logRes["level"] != tt.expectedLevel || logRes["msg"] != tt.expectedMsg {
+				t.Errorf(
+					"expected level %s and msg %q, got %v and %v",
+					tt.expectedLevel, tt.expectedMsg, logRes["level"], logRes["msg"],
+				)
+			}
+			if logRes["http_status"] != tt.expectedHTTPStatus {
+				t.Errorf("expected http_status %v, got %v", tt.expectedHTTPStatus, logRes["http_status"])
+			}
+			for _, k := range []string{"request_id", "user_agent", "remote_addr", "uri"} {
+				if v, _ := logRes[k].(string); v == "" {
+					t.Errorf("expected a %s, got nothing", k)
+				}
+			}
+		})
+	}
+}
+
+func TestLoggerSlogRemoteAddr(t *testing.T) {
+	tp, err := NewTrustedProxies("10.0.0.0/8")
+	if err != nil {
+		t.Fatalf("NewTrustedProxies failed %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		mws      []Middleware
+		expected string
+	}{
+		{name: "peer address", expected: "10.0.0.1:1234"},
+		{name: "resolved by RealIP", mws: []Middleware{RealIP(tp)}, expected: "203.0.113.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			handler := NewChain(tt.mws...).
+				Append(LoggerSlog(slog.New(slog.NewJSONHandler(out, nil)))).
+				ThenFunc(func(w http.ResponseWriter, r *http.Request) {})
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = "10.0.0.1:1234"
+			r.Header.Set("X-Forwarded-For", "203.0.113.1")
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			var logRes map[string]interface{}
+			if err := json.Unmarshal(out.Bytes(), &logRes); err != nil {
+				t.Fatalf("error unmarshalling log %v", err)
+			}
+			if logRes["remote_addr"] != tt.expected {
+				t.Errorf("expected remote_addr %q, got %v", tt.expected, logRes["remote_addr"])
+			}
+		})
+	}
+}
+
+func TestLoggersAddToRequestLog(t *testing.T) {
+	tests := []struct {
+		name   string
+		logger func(out *bytes.Buffer) Middleware
+	}{
+		{
+			name: "slog",
+			logger: func(out *bytes.Buffer) Middleware {
+				return LoggerSlog(slog.New(slog.NewJSONHandler(out, nil)))
+			},
+		},
+		{
+			name: "zerolog",
+			logger: func(out *bytes.Buffer) Middleware {
+				return LoggerZero(zerolog.New(out))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			handler := NewChain(
+				RequestID(),
+				tt.logger(out),
+				AddToRequestLog("service", func(context.Context) interface{} { return "fish" }),
+			).ThenFunc(func(w http.ResponseWriter, r *http.Request) {})
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+			var logRes map[string]interface{}
+			if err := json.Unmarshal(out.Bytes(), &logRes); err != nil {
+				t.Fatalf("error unmarshalling log %v", err)
+			}
+			if logRes["service"] != "fish" {
+				t.Errorf("expected the service field, got %v", logRes["service"])
+			}
+			if v, _ := logRes["request_id"].(string); v == "" {
+				t.Errorf("expected a request_id, got nothing")
+			}
+		})
+	}
+}
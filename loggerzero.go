@@ -1,13 +1,17 @@
 package gohttpmw
 
 import (
+	"io"
+	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/rs/zerolog"
 )
 
-// LoggerZero will log the full request details, with performance
+// LoggerZero will log the full request details, with performance.
+// The handlers log with the same logger through LogFromContext
+// or LogZeroFromContext
 func LoggerZero(logger zerolog.Logger) func(http.Handler) http.Handler {
 	return func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -15,30 +19,29 @@ func LoggerZero(logger zerolog.Logger) func(http.Handler) http.Handler {
 			startTime := time.Now()
 			naw := newAugmentedResponseWriter(w)
 
-			h.ServeHTTP(naw, r)
-
-			// Copy the context into a new logger
-			l := logger.With().Logger()
+			withRequestLogger(r, &requestLogger{
+				slog: slog.New(&slogZeroHandler{l: logger}),
+				zero: &logger,
+			})
 
-			if reqID := GetRequestID(r.Context()); reqID != "" {
-				l = l.With().Str("request_id", reqID).Logger()
-			}
+			h.ServeHTTP(naw, r)
 
-			scheme := "http"
-			if r.TLS != nil {
-				scheme = "https"
+			ctx := logger.With()
+			for _, f := range accessLogFields(r, naw, startTime) {
+				switch v := f.value.(type) {
+				case string:
+					ctx = ctx.Str(f.key, v)
+				case int:
+					ctx = ctx.Int(f.key, v)
+				case float64:
+					ctx = ctx.Float64(f.key, v)
+				case time.Duration:
+					ctx = ctx.Dur(f.key, v)
+				default:
+					ctx = ctx.Interface(f.key, v)
+				}
 			}
-			l = l.With().
-				Str("http_scheme", scheme).
-				Str("http_proto", r.Proto).
-				Str("http_method", r.Method).
-				Str("remote_addr", r.RemoteAddr).
-				Str("user_agent", r.UserAgent()).
-				Str("host", r.Host).
-				Str("uri", r.RequestURI).
-				Dur("process_time", time.Since(startTime)).
-				Int("http_status", naw.httpStatus).
-				Int("resp_length", naw.length).Logger()
+			l := ctx.Logger()
 
 			reqErr := GetRequestError(r.Context())
 			if reqErr != nil {
@@ -55,3 +58,16 @@ func LoggerZero(logger zerolog.Logger) func(http.Handler) http.Handler {
 		})
 	}
 }
+
+// LoggerZeroAsync is LoggerZero writing to w from a background goroutine
+// through an AsyncWriter, so that a slow w doesn't slow down the responses.
+// The AsyncWriter is to be closed on shutdown to write the last logs
+func LoggerZeroAsync(
+	logger zerolog.Logger,
+	w io.Writer,
+	opts ...AsyncWriterOption,
+) (func(http.Handler) http.Handler, *AsyncWriter) {
+	aw := NewAsyncWriter(w, opts...)
+
+	return LoggerZero(logger.Output(aw)), aw
+}
@@ -25,6 +25,7 @@ func TestLoggerZero(t *testing.T) {
 		expectedHTTPStatus int
 		withHTTPS          bool
 		withRequestID      bool
+		expectedUserID     string
 	}{
 		{
 			name: "classic request log",
@@ -84,6 +85,19 @@ func TestLoggerZero(t *testing.T) {
 			expectedHTTPStatus: http.StatusOK,
 			withRequestID:      true,
 		},
+		{
+			name: "request log with principal",
+			handler: http.HandlerFunc(
+				func(w http.ResponseWriter, req *http.Request) {
+					*req = *req.WithContext(
+						WithPrincipal(req.Context(), &Principal{ID: "pollux"}),
+					)
+					w.WriteHeader(http.StatusOK)
+				}),
+			expectedLogLevel:   zerolog.InfoLevel,
+			expectedHTTPStatus: http.StatusOK,
+			expectedUserID:     "pollux",
+		},
 		{
 			name: "404 request log",
 			handler: http.HandlerFunc(
@@ -151,6 +165,14 @@ func TestLoggerZero(t *testing.T) {
 				return
 			}
 
+			if tt.expectedUserID != "" && logRes["user_id"] != tt.expectedUserID {
+				t.Errorf(
+					"expected user_id %s, got %v",
+					tt.expectedUserID, logRes["user_id"],
+				)
+				return
+			}
+
 			if tt.withRequestID {
 				val, ok := logRes["request_id"]
 				if !ok {
@@ -165,3 +187,23 @@ func TestLoggerZero(t *testing.T) {
 		})
 	}
 }
+
+func TestLoggerZeroAsync(t *testing.T) {
+	out := &bytes.Buffer{}
+	mw, aw := LoggerZeroAsync(zerolog.New(nil), out)
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if err := aw.Close(); err != nil {
+		t.Fatalf("Close failed %v", err)
+	}
+
+	logRes := make(map[string]interface{})
+	if err := json.Unmarshal(out.Bytes(), &logRes); err != nil {
+		t.Fatalf("error unmarshalling log %v", err)
+	}
+	if logRes["http_status"] != float64(http.StatusTeapot) {
+		t.Errorf("expected http_status %d, got %v", http.StatusTeapot, logRes["http_status"])
+	}
+}
@@ -0,0 +1,166 @@
+package gohttpmw
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// HTTPMetrics holds the prometheus collectors fed by the Metrics middleware
+type HTTPMetrics struct {
+	routeName func(*http.Request) string
+
+	requests     *prometheus.CounterVec
+	duration     *prometheus.HistogramVec
+	requestSize  *prometheus.HistogramVec
+	responseSize *prometheus.HistogramVec
+	inFlight     prometheus.Gauge
+}
+
+// MetricsOption configures the HTTPMetrics
+type MetricsOption func(*metricsConfig)
+
+type metricsConfig struct {
+	namespace       string
+	durationBuckets []float64
+	sizeBuckets     []float64
+}
+
+// WithMetricsNamespace prefixes the metric names with namespace
+func WithMetricsNamespace(namespace string) MetricsOption {
+	return func(c *metricsConfig) {
+		c.namespace = namespace
+	}
+}
+
+// WithDurationBuckets sets the buckets, in seconds, of the duration histogram
+func WithDurationBuckets(buckets []float64) MetricsOption {
+	return func(c *metricsConfig) {
+		c.durationBuckets = buckets
+	}
+}
+
+// WithSizeBuckets sets the buckets, in bytes, of the size histograms
+func WithSizeBuckets(buckets []float64) MetricsOption {
+	return func(c *metricsConfig) {
+		c.sizeBuckets = buckets
+	}
+}
+
+// NewHTTPMetrics creates the collectors and registers them on reg.
+// routeName gives the route label of a request, it is called after
+// the handler so routers can fill in the matched pattern, and must
+// return a bounded set of values to avoid a cardinality explosion,
+// if nil the route label is left empty
+func NewHTTPMetrics(
+	reg prometheus.Registerer,
+	routeName func(*http.Request) string,
+	opts ...MetricsOption,
+) (*HTTPMetrics, error) {
+	cfg := metricsConfig{
+		durationBuckets: prometheus.DefBuckets,
+		sizeBuckets:     prometheus.ExponentialBuckets(100, 10, 7),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if routeName == nil {
+		routeName = func(*http.Request) string { return "" }
+	}
+
+	labels := []string{"method", "status_class", "route"}
+	m := &HTTPMetrics{
+		routeName: routeName,
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: cfg.namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of http requests handled.",
+		}, labels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: cfg.namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Duration of the http requests.",
+			Buckets:   cfg.durationBuckets,
+		}, labels),
+		requestSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: cfg.namespace,
+			Name:      "http_request_size_bytes",
+			Help:      "Size of the http request bodies.",
+			Buckets:   cfg.sizeBuckets,
+		}, labels),
+		responseSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: cfg.namespace,
+			Name:      "http_response_size_bytes",
+			Help:      "Size of the http response bodies.",
+			Buckets:   cfg.sizeBuckets,
+		}, labels),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: cfg.namespace,
+			Name:      "http_requests_in_flight",
+			Help:      "Number of http requests being handled.",
+		}),
+	}
+
+	for _, c := range []prometheus.Collector{
+		m.requests, m.duration, m.requestSize, m.responseSize, m.inFlight,
+	} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// Metrics records the requests in the prometheus collectors of m
+func Metrics(m *HTTPMetrics) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			m.inFlight.Inc()
+			defer m.inFlight.Dec()
+
+			naw := newAugmentedResponseWriter(w)
+			body := &countingReadCloser{ReadCloser: r.Body}
+			if r.Body != nil {
+				r.Body = body
+			}
+			startTime := time.Now()
+
+			h.ServeHTTP(naw, r)
+
+			labels := prometheus.Labels{
+				"method":       r.Method,
+				"status_class": strconv.Itoa(naw.httpStatus/100) + "xx",
+				"route":        m.routeName(r),
+			}
+			m.requests.With(labels).Inc()
+			m.duration.With(labels).Observe(time.Since(startTime).Seconds())
+			m.requestSize.With(labels).Observe(float64(body.n))
+			m.responseSize.With(labels).Observe(float64(naw.length))
+		})
+	}
+}
+
+// MetricsHandler exposes the metrics gathered by g in the prometheus format,
+// to be mounted on /metrics
+func MetricsHandler(g prometheus.Gatherer) http.Handler {
+	return promhttp.HandlerFor(g, promhttp.HandlerOpts{})
+}
+
+// countingReadCloser counts the bytes read from the request body
+type countingReadCloser struct {
+	io.ReadCloser
+	n int64
+}
+
+func (c *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n += int64(n)
+
+	return n, err
+}
@@ -0,0 +1,123 @@
+package gohttpmw
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m, err := NewHTTPMetrics(
+		reg,
+		func(r *http.Request) string { return "/users/{id}" },
+		WithMetricsNamespace("test"),
+	)
+	if err != nil {
+		t.Fatalf("NewHTTPMetrics failed %v", err)
+	}
+
+	var inFlight float64
+	fakeHandler := http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			inFlight = testutil.ToFloat64(m.inFlight)
+			_, _ = io.ReadAll(req.Body)
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("not"))
+			_, _ = w.Write([]byte(" found"))
+		},
+	)
+	midWared := Metrics(m)(fakeHandler)
+
+	for i := 0; i < 2; i++ {
+		rr := httptest.NewRecorder()
+		request := httptest.NewRequest(
+			http.MethodPost, "/users/1", strings.NewReader("hello"),
+		)
+		midWared.ServeHTTP(rr, request)
+	}
+
+	if inFlight != 1 {
+		t.Errorf("expected 1 request in flight during handling, got %f", inFlight)
+	}
+	if v := testutil.ToFloat64(m.inFlight); v != 0 {
+		t.Errorf("expected no request in flight after handling, got %f", v)
+	}
+
+	labels := prometheus.Labels{
+		"method": http.MethodPost, "status_class": "4xx", "route": "/users/{id}",
+	}
+	if v := testutil.ToFloat64(m.requests.With(labels)); v != 2 {
+		t.Errorf("expected 2 requests counted, got %f", v)
+	}
+
+	expected := `
+# HELP test_http_response_size_bytes Size of the http response bodies.
+# TYPE test_http_response_size_bytes histogram
+test_http_response_size_bytes_bucket{method="POST",route="/users/{id}",status_class="4xx",le="100"} 2
+test_http_response_size_bytes_bucket{method="POST",route="/users/{id}",status_class="4xx",le="1000"} 2
+test_http_response_size_bytes_bucket{method="POST",route="/users/{id}",status_class="4xx",le="10000"} 2
+test_http_response_size_bytes_bucket{method="POST",route="/users/{id}",status_class="4xx",le="100000"} 2
+test_http_response_size_bytes_bucket{method="POST",route="/users/{id}",status_class="4xx",le="1e+06"} 2
+test_http_response_size_bytes_bucket{method="POST",route="/users/{id}",status_class="4xx",le="1e+07"} 2
+test_http_response_size_bytes_bucket{method="POST",route="/users/{id}",status_class="4xx",le="1e+08"} 2
+test_http_response_size_bytes_bucket{method="POST",route="/users/{id}",status_class="4xx",le="+Inf"} 2
+test_http_response_size_bytes_sum{method="POST",route="/users/{id}",status_class="4xx"} 18
+test_http_response_size_bytes_count{method="POST",route="/users/{id}",status_class="4xx"} 2
+# HELP test_http_request_size_bytes Size of the http request bodies.
+# TYPE test_http_request_size_bytes histogram
+test_http_request_size_bytes_bucket{method="POST",route="/users/{id}",status_class="4xx",le="100"} 2
+test_http_request_size_bytes_bucket{method="POST",route="/users/{id}",status_class="4xx",le="1000"} 2
+test_http_request_size_bytes_bucket{method="POST",route="/users/{id}",status_class="4xx",le="10000"} 2
+test_http_request_size_bytes_bucket{method="POST",route="/users/{id}",status_class="4xx",le="100000"} 2
+test_http_request_size_bytes_bucket{method="POST",route="/users/{id}",status_class="4xx",le="1e+06"} 2
+test_http_request_size_bytes_bucket{method="POST",route="/users/{id}",status_class="4xx",le="1e+07"} 2
+test_http_request_size_bytes_bucket{method="POST",route="/users/{id}",status_class="4xx",le="1e+08"} 2
+test_http_request_size_bytes_bucket{method="POST",route="/users/{id}",status_class="4xx",le="+Inf"} 2
+test_http_request_size_bytes_sum{method="POST",route="/users/{id}",status_class="4xx"} 10
+test_http_request_size_bytes_count{method="POST",route="/users/{id}",status_class="4xx"} 2
+`
+	if err := testutil.GatherAndCompare(
+		reg, strings.NewReader(expected),
+		"test_http_response_size_bytes", "test_http_request_size_bytes",
+	); err != nil {
+		t.Errorf("unexpected size metrics %v", err)
+	}
+
+	if _, err := NewHTTPMetrics(reg, nil, WithMetricsNamespace("test")); err == nil {
+		t.Errorf("expected an error registering the metrics twice")
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m, err := NewHTTPMetrics(reg, nil)
+	if err != nil {
+		t.Fatalf("NewHTTPMetrics failed %v", err)
+	}
+	fakeHandler := http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {},
+	)
+	Metrics(m)(fakeHandler).ServeHTTP(
+		httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil),
+	)
+
+	rr := httptest.NewRecorder()
+	MetricsHandler(reg).ServeHTTP(
+		rr, httptest.NewRequest(http.MethodGet, "/metrics", nil),
+	)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rr.Code)
+	}
+	if !strings.Contains(
+		rr.Body.String(),
+		`http_requests_total{method="GET",route="",status_class="2xx"} 1`,
+	) {
+		t.Errorf("expected request counter in output, got %s", rr.Body.String())
+	}
+}
@@ -0,0 +1,75 @@
+package gohttpmw
+
+import (
+	"context"
+)
+
+const (
+	// ContextKeyPrincipal allow storage of the authenticated principal
+	// in the context
+	ContextKeyPrincipal = ContextKey("principal")
+)
+
+// Authentication methods set by the auth middlewares of this package
+const (
+	AuthMethodJWT    = "jwt"
+	AuthMethodAPIKey = "apikey"
+	AuthMethodBasic  = "basic"
+)
+
+// Principal is the authenticated identity behind a request,
+// it is set by the auth middlewares and consumed by RBAC and the loggers
+type Principal struct {
+	ID         string
+	Roles      []string
+	Scopes     []string
+	AuthMethod string
+	Attributes map[string]interface{}
+}
+
+// HasScope tells if the principal was granted the scope
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WithPrincipal returns a copy of ctx holding the principal p,
+// the request logger gets its user_id
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return withRequestLoggerPrincipal(
+		context.WithValue(ctx, ContextKeyPrincipal, p),
+		p,
+	)
+}
+
+// GetPrincipal will retrieve the principal from the context if there is one
+func GetPrincipal(ctx context.Context) *Principal {
+	if p, ok := ctx.Value(ContextKeyPrincipal).(*Principal); ok {
+		return p
+	}
+
+	return nil
+}
+
+// PrincipalRole is a role extractor for RBAC using the principal's first role
+func PrincipalRole(ctx context.Context) string {
+	if p := GetPrincipal(ctx); p != nil && len(p.Roles) > 0 {
+		return p.Roles[0]
+	}
+
+	return ""
+}
+
+// principalID is used to add the user_id to the access log
+func principalID(ctx context.Context) string {
+	if p := GetPrincipal(ctx); p != nil {
+		return p.ID
+	}
+
+	return ""
+}
@@ -0,0 +1,57 @@
+package gohttpmw
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGetPrincipal(t *testing.T) {
+	ctx := context.Background()
+	if p := GetPrincipal(ctx); p != nil {
+		t.Errorf("expected nothing, got %v", p)
+		return
+	}
+
+	testP := &Principal{ID: "pollux"}
+	if p := GetPrincipal(WithPrincipal(ctx, testP)); p != testP {
+		t.Errorf("expected %v, got %v", testP, p)
+		return
+	}
+}
+
+func TestPrincipalRole(t *testing.T) {
+	tests := []struct {
+		name string
+		p    *Principal
+		want string
+	}{
+		{name: "no principal", want: ""},
+		{name: "no roles", p: &Principal{ID: "pollux"}, want: ""},
+		{
+			name: "first role",
+			p:    &Principal{ID: "pollux", Roles: []string{"admin", "viewer"}},
+			want: "admin",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.p != nil {
+				ctx = WithPrincipal(ctx, tt.p)
+			}
+			if got := PrincipalRole(ctx); got != tt.want {
+				t.Errorf("PrincipalRole() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrincipal_HasScope(t *testing.T) {
+	p := &Principal{Scopes: []string{"read", "write"}}
+	if !p.HasScope("write") {
+		t.Errorf("expected scope write")
+	}
+	if p.HasScope("admin") {
+		t.Errorf("expected no scope admin")
+	}
+}
@@ -0,0 +1,99 @@
+package gohttpmw
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrRateLimited is recorded as the request error of throttled requests
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// RateLimitResult is the outcome of taking one request from a limit
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the limit is fully available again
+	Reset time.Duration
+	// RetryAfter is the time until the next request is allowed,
+	// only set when the request is not allowed
+	RetryAfter time.Duration
+}
+
+// RateLimitStore counts the requests made for a key and tells
+// if one more is allowed, it can be implemented on top
+// of an external backend to share limits between instances
+type RateLimitStore interface {
+	Take(ctx context.Context, key string) (RateLimitResult, error)
+}
+
+// RateLimit throttles the requests sharing the same key, throttled requests
+// get a 429 with a Retry-After header and are recorded as request errors.
+// Requests with an empty key are not limited, and so are the requests for
+// which the store fails
+func RateLimit(
+	store RateLimitStore,
+	key func(*http.Request) string,
+) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			k := key(r)
+			if k == "" {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			res, err := store.Take(r.Context(), k)
+			if err != nil {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			w.Header().Set("RateLimit-Reset", ceilSeconds(res.Reset))
+
+			if !res.Allowed {
+				SetRequestError(r, ErrRateLimited)
+				w.Header().Set("Retry-After", ceilSeconds(res.RetryAfter))
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// KeyByRemoteIP limits the requests per client ip, as resolved by RealIP
+func KeyByRemoteIP(r *http.Request) string {
+	return "ip:" + clientIP(r)
+}
+
+// KeyByPrincipal limits the requests per authenticated principal,
+// unauthenticated requests are limited per client ip
+func KeyByPrincipal(r *http.Request) string {
+	if id := principalID(r.Context()); id != "" {
+		return "principal:" + id
+	}
+
+	return KeyByRemoteIP(r)
+}
+
+// KeyByAPIKey limits the requests per api key id,
+// requests without api key are limited per client ip
+func KeyByAPIKey(r *http.Request) string {
+	if apiKey := GetAPIKey(r.Context()); apiKey != nil {
+		return "apikey:" + apiKey.ID
+	}
+
+	return KeyByRemoteIP(r)
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,150 @@
+package gohttpmw
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	store := NewTokenBucketStore(2, time.Minute)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	fakeHandler := http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {},
+	)
+	midWared := RateLimit(store, KeyByRemoteIP)(fakeHandler)
+
+	tests := []struct {
+		name              string
+		remoteAddr        string
+		expectedStatus    int
+		expectedRemaining string
+		expectedRetry     string
+	}{
+		{
+			name:              "first request",
+			remoteAddr:        "10.0.0.1:1234",
+			expectedStatus:    http.StatusOK,
+			expectedRemaining: "1",
+		},
+		{
+			name:              "second request from another port",
+			remoteAddr:        "10.0.0.1:4321",
+			expectedStatus:    http.StatusOK,
+			expectedRemaining: "0",
+		},
+		{
+			name:              "third request limited",
+			remoteAddr:        "10.0.0.1:1234",
+			expectedStatus:    http.StatusTooManyRequests,
+			expectedRemaining: "0",
+			expectedRetry:     "30",
+		},
+		{
+			name:              "other client",
+			remoteAddr:        "10.0.0.2:1234",
+			expectedStatus:    http.StatusOK,
+			expectedRemaining: "1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, `/`, nil)
+			request.RemoteAddr = tt.remoteAddr
+			midWared.ServeHTTP(rr, request)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if got := rr.Header().Get("RateLimit-Limit"); got != "2" {
+				t.Errorf("expected RateLimit-Limit 2, got %s", got)
+			}
+			if got := rr.Header().Get("RateLimit-Remaining"); got != tt.expectedRemaining {
+				t.Errorf(
+					"expected RateLimit-Remaining %s, got %s",
+					tt.expectedRemaining, got,
+				)
+			}
+			if got := rr.Header().Get("Retry-After"); got != tt.expectedRetry {
+				t.Errorf("expected Retry-After %s, got %s", tt.expectedRetry, got)
+			}
+
+			reqErr := GetRequestError(request.Context())
+			if tt.expectedStatus == http.StatusTooManyRequests && reqErr != ErrRateLimited {
+				t.Errorf("expected %v, got %v", ErrRateLimited, reqErr)
+			}
+		})
+	}
+}
+
+func TestRateLimitSkipped(t *testing.T) {
+	fakeHandler := http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {},
+	)
+
+	tests := []struct {
+		name  string
+		store RateLimitStore
+		key   func(*http.Request) string
+	}{
+		{
+			name:  "empty key",
+			store: NewTokenBucketStore(0, time.Minute),
+			key:   func(*http.Request) string { return "" },
+		},
+		{
+			name:  "store failure",
+			store: failingRateLimitStore{},
+			key:   KeyByRemoteIP,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			RateLimit(tt.store, tt.key)(fakeHandler).ServeHTTP(
+				rr, httptest.NewRequest(http.MethodGet, `/`, nil),
+			)
+			if rr.Code != http.StatusOK {
+				t.Errorf("expected request not to be limited, got %d", rr.Code)
+			}
+		})
+	}
+}
+
+func TestRateLimitKeys(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, `/`, nil)
+	request.RemoteAddr = "10.0.0.1:1234"
+
+	if k := KeyByRemoteIP(request); k != "ip:10.0.0.1" {
+		t.Errorf("expected ip key, got %s", k)
+	}
+	if k := KeyByPrincipal(request); k != "ip:10.0.0.1" {
+		t.Errorf("expected ip fallback key, got %s", k)
+	}
+	if k := KeyByAPIKey(request); k != "ip:10.0.0.1" {
+		t.Errorf("expected ip fallback key, got %s", k)
+	}
+
+	ctx := WithPrincipal(request.Context(), &Principal{ID: "pollux"})
+	ctx = context.WithValue(ctx, ContextKeyAPIKey, &APIKey{ID: "key1"})
+	request = request.WithContext(ctx)
+	if k := KeyByPrincipal(request); k != "principal:pollux" {
+		t.Errorf("expected principal key, got %s", k)
+	}
+	if k := KeyByAPIKey(request); k != "apikey:key1" {
+		t.Errorf("expected api key key, got %s", k)
+	}
+}
+
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Take(context.Context, string) (RateLimitResult, error) {
+	return RateLimitResult{}, errors.New("store down")
+}
@@ -0,0 +1,182 @@
+package gohttpmw
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+)
+
+const rateLimitShards = 32
+
+// MemoryRateLimitStore is an in-memory RateLimitStore, the keys are spread
+// over shards to limit lock contention and idle keys are evicted
+type MemoryRateLimitStore struct {
+	limit  int
+	period time.Duration
+	take   rateLimitAlgorithm
+	now    func() time.Time
+	shards [rateLimitShards]rateLimitShard
+}
+
+// rateLimitAlgorithm takes one request from the entry e
+type rateLimitAlgorithm func(
+	e *rateLimitEntry,
+	limit int,
+	period time.Duration,
+	now time.Time,
+) RateLimitResult
+
+type rateLimitShard struct {
+	mu        sync.Mutex
+	entries   map[string]*rateLimitEntry
+	lastSweep time.Time
+}
+
+type rateLimitEntry struct {
+	// token bucket state
+	tokens float64
+	last   time.Time
+	// sliding window state
+	windowStart time.Time
+	prevCount   int
+	currCount   int
+
+	expires time.Time
+}
+
+// NewTokenBucketStore creates a token bucket limit allowing bursts of limit
+// requests, refilled at a rate of limit requests per period
+func NewTokenBucketStore(limit int, period time.Duration) *MemoryRateLimitStore {
+	return newMemoryRateLimitStore(limit, period, takeTokenBucket)
+}
+
+// NewSlidingWindowStore creates a sliding window limit of limit requests
+// per window, the window is approximated from the counts of the current
+// and previous fixed windows
+func NewSlidingWindowStore(limit int, window time.Duration) *MemoryRateLimitStore {
+	return newMemoryRateLimitStore(limit, window, takeSlidingWindow)
+}
+
+func newMemoryRateLimitStore(
+	limit int,
+	period time.Duration,
+	take rateLimitAlgorithm,
+) *MemoryRateLimitStore {
+	s := &MemoryRateLimitStore{
+		limit:  limit,
+		period: period,
+		take:   take,
+		now:    time.Now,
+	}
+	for i := range s.shards {
+		s.shards[i].entries = make(map[string]*rateLimitEntry)
+	}
+
+	return s
+}
+
+// Take counts one request for key
+func (s *MemoryRateLimitStore) Take(
+	_ context.Context,
+	key string,
+) (RateLimitResult, error) {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	shard := &s.shards[h.Sum32()%rateLimitShards]
+	now := s.now()
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if now.Sub(shard.lastSweep) > s.period {
+		for k, e := range shard.entries {
+			if now.After(e.expires) {
+				delete(shard.entries, k)
+			}
+		}
+		shard.lastSweep = now
+	}
+
+	e, ok := shard.entries[key]
+	if !ok {
+		e = &rateLimitEntry{tokens: float64(s.limit), last: now}
+		shard.entries[key] = e
+	}
+
+	return s.take(e, s.limit, s.period, now), nil
+}
+
+func takeTokenBucket(
+	e *rateLimitEntry,
+	limit int,
+	period time.Duration,
+	now time.Time,
+) RateLimitResult {
+	// tokens per second
+	rate := float64(limit) / period.Seconds()
+	e.tokens = math.Min(
+		float64(limit),
+		e.tokens+now.Sub(e.last).Seconds()*rate,
+	)
+	e.last = now
+
+	res := RateLimitResult{Limit: limit}
+	if e.tokens >= 1 {
+		e.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = secondsToDuration((1 - e.tokens) / rate)
+	}
+	res.Remaining = int(e.tokens)
+	res.Reset = secondsToDuration((float64(limit) - e.tokens) / rate)
+	e.expires = now.Add(res.Reset)
+
+	return res
+}
+
+func takeSlidingWindow(
+	e *rateLimitEntry,
+	limit int,
+	window time.Duration,
+	now time.Time,
+) RateLimitResult {
+	start := now.Truncate(window)
+	switch {
+	case start.Equal(e.windowStart):
+	case start.Sub(e.windowStart) == window:
+		e.prevCount, e.currCount = e.currCount, 0
+	default:
+		e.prevCount, e.currCount = 0, 0
+	}
+	e.windowStart = start
+
+	elapsed := now.Sub(start)
+	weight := 1 - float64(elapsed)/float64(window)
+	estimated := float64(e.prevCount)*weight + float64(e.currCount)
+
+	res := RateLimitResult{Limit: limit}
+	if estimated+1 <= float64(limit) {
+		e.currCount++
+		estimated++
+		res.Allowed = true
+	} else {
+		res.RetryAfter = window - elapsed
+		// The previous window may fade out enough before the current one ends
+		if free := float64(limit - 1 - e.currCount); free >= 0 && e.prevCount > 0 {
+			res.RetryAfter = time.Duration(
+				(1-free/float64(e.prevCount))*float64(window),
+			) - elapsed
+		}
+	}
+	res.Remaining = int(math.Max(0, math.Floor(float64(limit)-estimated)))
+	res.Reset = 2*window - elapsed
+	e.expires = start.Add(2 * window)
+
+	return res
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,101 @@
+package gohttpmw
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketStore(t *testing.T) {
+	store := NewTokenBucketStore(3, 3*time.Second)
+	now := time.Unix(1000, 0)
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	steps := []struct {
+		advance           time.Duration
+		expectedAllowed   bool
+		expectedRemaining int
+		expectedRetry     time.Duration
+	}{
+		{expectedAllowed: true, expectedRemaining: 2},
+		{expectedAllowed: true, expectedRemaining: 1},
+		{expectedAllowed: true, expectedRemaining: 0},
+		{expectedAllowed: false, expectedRemaining: 0, expectedRetry: time.Second},
+		{
+			advance:         500 * time.Millisecond,
+			expectedAllowed: false, expectedRemaining: 0,
+			expectedRetry: 500 * time.Millisecond,
+		},
+		{advance: 500 * time.Millisecond, expectedAllowed: true, expectedRemaining: 0},
+		{advance: 10 * time.Second, expectedAllowed: true, expectedRemaining: 2},
+	}
+
+	for i, step := range steps {
+		now = now.Add(step.advance)
+		res, err := store.Take(ctx, "key")
+		if err != nil {
+			t.Fatalf("step %d: unexpected error %v", i, err)
+		}
+		if res.Allowed != step.expectedAllowed ||
+			res.Remaining != step.expectedRemaining ||
+			res.RetryAfter != step.expectedRetry {
+			t.Errorf("step %d: unexpected result %+v", i, res)
+		}
+	}
+}
+
+func TestSlidingWindowStore(t *testing.T) {
+	store := NewSlidingWindowStore(4, time.Minute)
+	now := time.Unix(6000, 0)
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 0; i < 4; i++ {
+		if res, _ := store.Take(ctx, "key"); !res.Allowed {
+			t.Fatalf("request %d: expected to be allowed", i)
+		}
+	}
+	res, _ := store.Take(ctx, "key")
+	if res.Allowed || res.RetryAfter != time.Minute {
+		t.Errorf("expected to be limited until the next window, got %+v", res)
+	}
+
+	// Half way through the next window, half of the previous count remains
+	now = now.Add(90 * time.Second)
+	for i := 0; i < 2; i++ {
+		if res, _ := store.Take(ctx, "key"); !res.Allowed {
+			t.Fatalf("request %d: expected to be allowed", i)
+		}
+	}
+	res, _ = store.Take(ctx, "key")
+	if res.Allowed {
+		t.Errorf("expected to be limited, got %+v", res)
+	}
+	// One request frees up when the previous window weighs a quarter
+	if res.RetryAfter != 15*time.Second {
+		t.Errorf("expected retry after 15s, got %v", res.RetryAfter)
+	}
+
+	now = now.Add(3 * time.Minute)
+	if res, _ := store.Take(ctx, "key"); !res.Allowed || res.Remaining != 3 {
+		t.Errorf("expected a fresh window, got %+v", res)
+	}
+}
+
+func TestMemoryRateLimitStoreEviction(t *testing.T) {
+	store := NewSlidingWindowStore(1, time.Minute)
+	now := time.Unix(6000, 0)
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, _ = store.Take(ctx, "idle")
+	now = now.Add(3 * time.Minute)
+	_, _ = store.Take(ctx, "idle2")
+
+	for i := range store.shards {
+		if _, ok := store.shards[i].entries["idle"]; ok {
+			t.Errorf("expected idle key to be evicted")
+		}
+	}
+}
@@ -5,20 +5,66 @@ import (
 	"net/http"
 
 	"github.com/ory/ladon"
+	pkgerrors "github.com/pkg/errors"
 )
 
-// RBAC checks if the user is allowed to do the request
+// RBACOption configures the RBAC middleware
+type RBACOption func(*rbacConfig)
+
+type rbacConfig struct {
+	auditSink      AuditSink
+	shadowWarden   ladon.Warden
+	shadowReporter ShadowReporter
+	resource       func(*http.Request) string
+}
+
+// WithAuditSink makes RBAC send an audit event for every access decision
+func WithAuditSink(s AuditSink) RBACOption {
+	return func(c *rbacConfig) {
+		c.auditSink = s
+	}
+}
+
+// WithResource maps the requests to the resources of the policies,
+// by default the resource is the request uri
+func WithResource(f func(*http.Request) string) RBACOption {
+	return func(c *rbacConfig) {
+		c.resource = f
+	}
+}
+
+// RBAC checks if the user is allowed to do the request,
+// if getRoleFunc is nil the roles of the principal in the context are used:
+// the request is denied if one of them is explicitly denied,
+// else allowed if one of them is allowed
 func RBAC(
 	warden ladon.Warden,
 	getRoleFunc func(context.Context) string,
+	opts ...RBACOption,
 ) func(http.Handler) http.Handler {
+	cfg := rbacConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
 	return func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if err := warden.IsAllowed(&ladon.Request{
-				Subject:  getRoleFunc(r.Context()),
-				Action:   r.Method,
-				Resource: r.RequestURI,
-			}); err != nil {
+			resource := r.RequestURI
+			if cfg.resource != nil {
+				resource = cfg.resource(r)
+			}
+			subjects := rbacSubjects(r.Context(), getRoleFunc)
+			req, policyIDs, err := decide(
+				warden, subjects, r.Method, resource, rbacContext(r),
+			)
+			if cfg.auditSink != nil {
+				cfg.auditSink.Audit(newAuditEvent(r, req, policyIDs, err))
+			}
+			if cfg.shadowWarden != nil {
+				cfg.shadow(r, subjects, req, policyIDs, err)
+			}
+
+			if err != nil {
 				w.WriteHeader(http.StatusForbidden)
 				return
 			}
@@ -27,3 +73,74 @@ func RBAC(
 		})
 	}
 }
+
+func rbacSubjects(
+	ctx context.Context,
+	getRoleFunc func(context.Context) string,
+) []string {
+	if getRoleFunc != nil {
+		return []string{getRoleFunc(ctx)}
+	}
+	if p := GetPrincipal(ctx); p != nil && len(p.Roles) > 0 {
+		return p.Roles
+	}
+
+	return []string{""}
+}
+
+// rbacContext exposes the client info to the policy conditions,
+// such as a ladon.CIDRCondition on remoteIPAddress
+func rbacContext(r *http.Request) ladon.Context {
+	return ladon.Context{
+		"remoteIPAddress": clientIP(r),
+		"scheme":          requestScheme(r),
+		"host":            requestHost(r),
+	}
+}
+
+// decide asks the warden for every subject, as in ladon an explicit
+// deny of one of them overrides the others. It returns the deciding
+// request: the denied one, else the first allowed, else the first one
+func decide(
+	warden ladon.Warden,
+	subjects []string,
+	action string,
+	resource string,
+	ctx ladon.Context,
+) (*ladon.Request, []string, error) {
+	type decision struct {
+		req       *ladon.Request
+		policyIDs []string
+		err       error
+	}
+	var allowed, denied *decision
+	for _, subject := range subjects {
+		d := &decision{req: &ladon.Request{
+			Subject:  subject,
+			Action:   action,
+			Resource: resource,
+			Context:  ctx,
+		}}
+		d.policyIDs, d.err = isAllowedWithDeciders(warden, d.req)
+		switch {
+		case d.err == nil:
+			if allowed == nil {
+				allowed = d
+			}
+		case pkgerrors.Cause(d.err) == ladon.ErrRequestDenied:
+			// No policy matched this subject, ladon wraps its errors
+			// with github.com/pkg/errors, without Unwrap before v0.9
+			if denied == nil {
+				denied = d
+			}
+		default:
+			// Forcefully denied, or the warden failed
+			return d.req, d.policyIDs, d.err
+		}
+	}
+	if allowed != nil {
+		return allowed.req, allowed.policyIDs, nil
+	}
+
+	return denied.req, denied.policyIDs, denied.err
+}
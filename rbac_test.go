@@ -4,6 +4,8 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/ory/ladon"
@@ -79,6 +81,140 @@ func TestRBAC(t *testing.T) {
 	}
 }
 
+func TestRBACPrincipalRoles(t *testing.T) {
+	fakeHandler := http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {},
+	)
+
+	warden := &ladon.Ladon{
+		Manager: manager.NewMemoryManager(),
+	}
+	_ = warden.Manager.Create(
+		&ladon.DefaultPolicy{
+			ID:          ksuid.New().String(),
+			Description: "Test GET",
+			Subjects:    []string{"admin"},
+			Resources: []string{
+				"https://pol.com/test",
+			},
+			Actions: []string{"GET"},
+			Effect:  ladon.AllowAccess,
+		},
+	)
+	_ = warden.Manager.Create(
+		&ladon.DefaultPolicy{
+			ID:          ksuid.New().String(),
+			Description: "Test suspended",
+			Subjects:    []string{"suspended"},
+			Resources: []string{
+				"https://pol.com/test",
+			},
+			Actions: []string{"GET"},
+			Effect:  ladon.DenyAccess,
+		},
+	)
+
+	var audited AuditEvent
+	midWared := RBAC(warden, nil, WithAuditSink(AuditSinkFunc(func(e AuditEvent) {
+		audited = e
+	})))(fakeHandler)
+
+	tests := []struct {
+		name            string
+		principal       *Principal
+		allowed         bool
+		expectedSubject string
+	}{
+		{
+			name:            "allowed by its second role",
+			principal:       &Principal{ID: "pollux", Roles: []string{"viewer", "admin"}},
+			allowed:         true,
+			expectedSubject: "admin",
+		},
+		{
+			name:            "no allowed role",
+			principal:       &Principal{ID: "pollux", Roles: []string{"viewer"}},
+			allowed:         false,
+			expectedSubject: "viewer",
+		},
+		{
+			name:            "denied by its first role",
+			principal:       &Principal{ID: "pollux", Roles: []string{"suspended", "admin"}},
+			allowed:         false,
+			expectedSubject: "suspended",
+		},
+		{
+			name:            "denied by its last role",
+			principal:       &Principal{ID: "pollux", Roles: []string{"admin", "viewer", "suspended"}},
+			allowed:         false,
+			expectedSubject: "suspended",
+		},
+		{
+			name:    "no principal",
+			allowed: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.principal != nil {
+				ctx = WithPrincipal(ctx, tt.principal)
+			}
+			request := httptest.NewRequest("GET", "https://pol.com/test", nil)
+			rr := httptest.NewRecorder()
+
+			midWared.ServeHTTP(rr, request.WithContext(ctx))
+			if tt.allowed && (rr.Code == http.StatusForbidden) ||
+				!tt.allowed && (rr.Code != http.StatusForbidden) {
+				t.Errorf("expected allowed %t, got %d", tt.allowed, rr.Code)
+			}
+			if audited.Subject != tt.expectedSubject {
+				t.Errorf("expected the deciding subject %q, got %q", tt.expectedSubject, audited.Subject)
+			}
+		})
+	}
+}
+
+func TestDecideLadonErrors(t *testing.T) {
+	// ladon returns its errors wrapped with github.com/pkg/errors
+	warden := &ladon.Ladon{
+		Manager: manager.NewMemoryManager(),
+	}
+	for _, p := range []*ladon.DefaultPolicy{
+		{ID: "admin", Subjects: []string{"admin"}, Effect: ladon.AllowAccess},
+		{ID: "suspended", Subjects: []string{"suspended"}, Effect: ladon.DenyAccess},
+	} {
+		p.Resources, p.Actions = []string{"users"}, []string{"GET"}
+		if err := warden.Manager.Create(p); err != nil {
+			t.Fatalf("Create failed %v", err)
+		}
+	}
+
+	tests := []struct {
+		subjects        []string
+		allowed         bool
+		expectedSubject string
+	}{
+		{subjects: []string{"viewer", "admin"}, allowed: true, expectedSubject: "admin"},
+		{subjects: []string{"admin", "viewer"}, allowed: true, expectedSubject: "admin"},
+		{subjects: []string{"viewer", "editor"}, allowed: false, expectedSubject: "viewer"},
+		{subjects: []string{"viewer", "admin", "suspended"}, allowed: false, expectedSubject: "suspended"},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.subjects, ","), func(t *testing.T) {
+			req, _, err := decide(warden, tt.subjects, "GET", "users", ladon.Context{})
+			if (err == nil) != tt.allowed {
+				t.Errorf("expected allowed %t, got %v", tt.allowed, err)
+			}
+			if req.Subject != tt.expectedSubject {
+				t.Errorf("expected the deciding subject %q, got %q", tt.expectedSubject, req.Subject)
+			}
+		})
+	}
+}
+
 func BenchmarkRBAC(b *testing.B) {
 	fakeHandler := http.HandlerFunc(
 		func(w http.ResponseWriter, req *http.Request) {},
@@ -96,7 +232,7 @@ func BenchmarkRBAC(b *testing.B) {
 				Description: "Test GET",
 				Subjects:    []string{"admin"},
 				Resources: []string{
-					"https://pol.com/test/" + string(i),
+					"https://pol.com/test/" + strconv.Itoa(i),
 				},
 				Actions: []string{"GET"},
 				Effect:  ladon.AllowAccess,
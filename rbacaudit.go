@@ -0,0 +1,209 @@
+package gohttpmw
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ory/ladon"
+	"github.com/rs/zerolog"
+	"github.com/sirupsen/logrus"
+)
+
+// AuditEvent describes a single access decision taken by RBAC
+type AuditEvent struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request_id,omitempty"`
+	Subject   string    `json:"subject"`
+	Action    string    `json:"action"`
+	Resource  string    `json:"resource"`
+	// Decision is either ladon.AllowAccess or ladon.DenyAccess
+	Decision string `json:"decision"`
+	// PolicyIDs are the ids of the policies that took the decision,
+	// only available when the warden is a *ladon.Ladon
+	PolicyIDs []string `json:"policy_ids,omitempty"`
+}
+
+// AuditSink receives the audit events, it is kept separate from
+// the access log
+type AuditSink interface {
+	Audit(AuditEvent)
+}
+
+// AuditSinkFunc allows the use of an ordinary function as an AuditSink
+type AuditSinkFunc func(AuditEvent)
+
+// Audit calls f(e)
+func (f AuditSinkFunc) Audit(e AuditEvent) {
+	f(e)
+}
+
+func newAuditEvent(
+	r *http.Request,
+	req *ladon.Request,
+	policyIDs []string,
+	err error,
+) AuditEvent {
+	decision := ladon.AllowAccess
+	if err != nil {
+		decision = ladon.DenyAccess
+	}
+
+	return AuditEvent{
+		Time:      time.Now(),
+		RequestID: GetRequestID(r.Context()),
+		Subject:   req.Subject,
+		Action:    req.Action,
+		Resource:  req.Resource,
+		Decision:  decision,
+		PolicyIDs: policyIDs,
+	}
+}
+
+// AuditLog writes the audit events to a logrus logger,
+// denied requests are logged as warnings
+func AuditLog(l *logrus.Logger) AuditSink {
+	return AuditSinkFunc(func(e AuditEvent) {
+		entry := l.WithFields(logrus.Fields{
+			"audit_time": e.Time,
+			"subject":    e.Subject,
+			"action":     e.Action,
+			"resource":   e.Resource,
+			"decision":   e.Decision,
+			"policy_ids": e.PolicyIDs,
+		})
+		if e.RequestID != "" {
+			entry = entry.WithField("request_id", e.RequestID)
+		}
+
+		if e.Decision == ladon.DenyAccess {
+			entry.Warnln("access denied")
+			return
+		}
+		entry.Infoln("access granted")
+	})
+}
+
+// AuditLogZero writes the audit events to a zerolog logger,
+// denied requests are logged as warnings
+func AuditLogZero(l zerolog.Logger) AuditSink {
+	return AuditSinkFunc(func(e AuditEvent) {
+		ev := l.Info()
+		msg := "access granted"
+		if e.Decision == ladon.DenyAccess {
+			ev = l.Warn()
+			msg = "access denied"
+		}
+
+		if e.RequestID != "" {
+			ev = ev.Str("request_id", e.RequestID)
+		}
+		ev.Time("audit_time", e.Time).
+			Str("subject", e.Subject).
+			Str("action", e.Action).
+			Str("resource", e.Resource).
+			Str("decision", e.Decision).
+			Strs("policy_ids", e.PolicyIDs).
+			Msg(msg)
+	})
+}
+
+// JSONAuditSink writes the audit events as JSON lines
+type JSONAuditSink struct {
+	mu  sync.Mutex
+	w   io.Writer
+	enc *json.Encoder
+}
+
+// NewJSONAuditSink creates an AuditSink writing one JSON object per line to w
+func NewJSONAuditSink(w io.Writer) *JSONAuditSink {
+	return &JSONAuditSink{w: w, enc: json.NewEncoder(w)}
+}
+
+// OpenAuditFile creates an AuditSink appending JSON lines to the named file,
+// the file is created if it doesn't exist
+func OpenAuditFile(name string) (*JSONAuditSink, error) {
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewJSONAuditSink(f), nil
+}
+
+// Audit writes e as a single JSON line, write errors are ignored
+// as the request has already been decided
+func (s *JSONAuditSink) Audit(e AuditEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_ = s.enc.Encode(e)
+}
+
+// Close closes the underlying writer if it is an io.Closer
+func (s *JSONAuditSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.w.(io.Closer); ok {
+		return c.Close()
+	}
+
+	return nil
+}
+
+// isAllowedWithDeciders asks the warden for a decision and, when the warden
+// is a *ladon.Ladon, also returns the ids of the policies that decided
+func isAllowedWithDeciders(
+	warden ladon.Warden,
+	req *ladon.Request,
+) ([]string, error) {
+	l, ok := warden.(*ladon.Ladon)
+	if !ok {
+		return nil, warden.IsAllowed(req)
+	}
+
+	// Shallow copy so the capture doesn't race with other requests
+	capture := &decidersCapture{next: l.AuditLogger}
+	lc := *l
+	lc.AuditLogger = capture
+	err := lc.IsAllowed(req)
+
+	return capture.policyIDs, err
+}
+
+// decidersCapture is a ladon.AuditLogger keeping the deciding policies,
+// and forwarding to the warden's own audit logger if any
+type decidersCapture struct {
+	next      ladon.AuditLogger
+	policyIDs []string
+}
+
+func (c *decidersCapture) LogRejectedAccessRequest(
+	r *ladon.Request,
+	pool ladon.Policies,
+	deciders ladon.Policies,
+) {
+	c.capture(deciders)
+	if c.next != nil {
+		c.next.LogRejectedAccessRequest(r, pool, deciders)
+	}
+}
+
+func (c *decidersCapture) LogGrantedAccessRequest(
+	r *ladon.Request,
+	pool ladon.Policies,
+	deciders ladon.Policies,
+) {
+	c.capture(deciders)
+	if c.next != nil {
+		c.next.LogGrantedAccessRequest(r, pool, deciders)
+	}
+}
+
+func (c *decidersCapture) capture(deciders ladon.Policies) {
+	for _, p := range deciders {
+		c.policyIDs = append(c.policyIDs, p.GetID())
+	}
+}
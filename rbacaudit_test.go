@@ -0,0 +1,202 @@
+package gohttpmw
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ory/ladon"
+	manager "github.com/ory/ladon/manager/memory"
+	"github.com/rs/zerolog"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestRBACAudit(t *testing.T) {
+	fakeHandler := http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {},
+	)
+
+	warden := &ladon.Ladon{
+		Manager: manager.NewMemoryManager(),
+	}
+	_ = warden.Manager.Create(
+		&ladon.DefaultPolicy{
+			ID:          "allow-admin-get",
+			Description: "Test GET",
+			Subjects:    []string{"admin"},
+			Resources: []string{
+				"https://pol.com/test",
+			},
+			Actions: []string{"GET"},
+			Effect:  ladon.AllowAccess,
+		},
+	)
+
+	tests := []struct {
+		name              string
+		warden            ladon.Warden
+		role              string
+		url               string
+		expectedDecision  string
+		expectedPolicyIDs []string
+	}{
+		{
+			name:              "allowed request",
+			warden:            warden,
+			role:              "admin",
+			url:               "https://pol.com/test",
+			expectedDecision:  ladon.AllowAccess,
+			expectedPolicyIDs: []string{"allow-admin-get"},
+		},
+		{
+			name:             "denied request",
+			warden:           warden,
+			role:             "pollux",
+			url:              "https://pol.com/test",
+			expectedDecision: ladon.DenyAccess,
+		},
+		{
+			name:             "custom warden, no policy ids",
+			warden:           denyAllWarden{},
+			role:             "admin",
+			url:              "https://pol.com/test",
+			expectedDecision: ladon.DenyAccess,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []AuditEvent
+			sink := AuditSinkFunc(func(e AuditEvent) {
+				events = append(events, e)
+			})
+			midWared := RequestID()(RBAC(tt.warden, getRole, WithAuditSink(sink))(fakeHandler))
+
+			ctx := context.WithValue(context.Background(), contextKeyRole, tt.role)
+			request := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			rr := httptest.NewRecorder()
+			midWared.ServeHTTP(rr, request.WithContext(ctx))
+
+			if len(events) != 1 {
+				t.Fatalf("got %d audit events instead of 1", len(events))
+			}
+			e := events[0]
+			if e.Decision != tt.expectedDecision {
+				t.Errorf(
+					"wrong decision, expected %s, got %s",
+					tt.expectedDecision, e.Decision,
+				)
+			}
+			if !reflect.DeepEqual(e.PolicyIDs, tt.expectedPolicyIDs) {
+				t.Errorf(
+					"wrong policy ids, expected %v, got %v",
+					tt.expectedPolicyIDs, e.PolicyIDs,
+				)
+			}
+			if e.Subject != tt.role || e.Action != http.MethodGet ||
+				e.Resource != tt.url || e.RequestID == "" ||
+				e.RequestID != rr.Header().Get("requestID") {
+				t.Errorf("incomplete audit event %+v", e)
+			}
+			if e.Time.IsZero() {
+				t.Errorf("expected a timestamp in audit event")
+			}
+		})
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	sink := AuditLog(logger)
+
+	sink.Audit(AuditEvent{Subject: "admin", Decision: ladon.AllowAccess})
+	if hook.LastEntry().Level != logrus.InfoLevel {
+		t.Errorf("expected info level, got %v", hook.LastEntry().Level)
+	}
+
+	sink.Audit(AuditEvent{
+		Subject:   "pollux",
+		Decision:  ladon.DenyAccess,
+		RequestID: "reqid",
+	})
+	if hook.LastEntry().Level != logrus.WarnLevel {
+		t.Errorf("expected warn level, got %v", hook.LastEntry().Level)
+	}
+	if hook.LastEntry().Data["request_id"] != "reqid" {
+		t.Errorf("missing request_id in audit log")
+	}
+}
+
+func TestAuditLogZero(t *testing.T) {
+	out := &bytes.Buffer{}
+	sink := AuditLogZero(zerolog.New(out))
+
+	sink.Audit(AuditEvent{Subject: "pollux", Decision: ladon.DenyAccess})
+
+	logRes := make(map[string]interface{})
+	if err := json.Unmarshal(out.Bytes(), &logRes); err != nil {
+		t.Fatalf("error unmarshalling log %v", err)
+	}
+	if logRes["level"] != zerolog.WarnLevel.String() {
+		t.Errorf("expected warn level, got %v", logRes["level"])
+	}
+	if logRes["subject"] != "pollux" {
+		t.Errorf("expected subject pollux, got %v", logRes["subject"])
+	}
+}
+
+func TestOpenAuditFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "audit.log")
+	sink, err := OpenAuditFile(name)
+	if err != nil {
+		t.Fatalf("OpenAuditFile failed %v", err)
+	}
+
+	e := AuditEvent{
+		Time:      time.Unix(0, 0).UTC(),
+		Subject:   "admin",
+		Action:    http.MethodGet,
+		Resource:  "/test",
+		Decision:  ladon.AllowAccess,
+		PolicyIDs: []string{"1"},
+	}
+	sink.Audit(e)
+	sink.Audit(e)
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close failed %v", err)
+	}
+
+	sink, err = OpenAuditFile(name)
+	if err != nil {
+		t.Fatalf("OpenAuditFile failed %v", err)
+	}
+	sink.Audit(e)
+	_ = sink.Close()
+
+	b, _ := os.ReadFile(name)
+	lines := bytes.Split(bytes.TrimSpace(b), []byte("\n"))
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 audit lines, got %d", len(lines))
+	}
+	var got AuditEvent
+	if err := json.Unmarshal(lines[2], &got); err != nil {
+		t.Fatalf("error unmarshalling audit line %v", err)
+	}
+	if !reflect.DeepEqual(got, e) {
+		t.Errorf("expected %+v, got %+v", e, got)
+	}
+}
+
+type denyAllWarden struct{}
+
+func (denyAllWarden) IsAllowed(*ladon.Request) error {
+	return ladon.ErrRequestDenied
+}
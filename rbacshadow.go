@@ -0,0 +1,112 @@
+package gohttpmw
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ory/ladon"
+	"github.com/rs/zerolog"
+	"github.com/sirupsen/logrus"
+)
+
+// ShadowDisagreement describes a request for which the shadow warden
+// didn't take the same decision as the enforcing one
+type ShadowDisagreement struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request_id,omitempty"`
+	Subject   string    `json:"subject"`
+	Action    string    `json:"action"`
+	Resource  string    `json:"resource"`
+	// Decisions are either ladon.AllowAccess or ladon.DenyAccess
+	EnforcedDecision  string   `json:"enforced_decision"`
+	ShadowDecision    string   `json:"shadow_decision"`
+	EnforcedPolicyIDs []string `json:"enforced_policy_ids,omitempty"`
+	ShadowPolicyIDs   []string `json:"shadow_policy_ids,omitempty"`
+}
+
+// ShadowReporter receives the disagreements between the enforcing
+// and the shadow warden
+type ShadowReporter func(ShadowDisagreement)
+
+// WithShadowWarden evaluates every request against a candidate warden
+// in addition to the enforcing one, disagreements are reported
+// but the candidate's decision is never enforced. A nil report
+// drops the disagreements
+func WithShadowWarden(candidate ladon.Warden, report ShadowReporter) RBACOption {
+	if report == nil {
+		report = func(ShadowDisagreement) {}
+	}
+
+	return func(c *rbacConfig) {
+		c.shadowWarden = candidate
+		c.shadowReporter = report
+	}
+}
+
+// shadow evaluates the subjects against the shadow warden and reports
+// if its decision differs from the enforced one
+func (c *rbacConfig) shadow(
+	r *http.Request,
+	subjects []string,
+	req *ladon.Request,
+	policyIDs []string,
+	err error,
+) {
+	sreq, shadowPolicyIDs, shadowErr := decide(
+		c.shadowWarden, subjects, req.Action, req.Resource, req.Context,
+	)
+	if (err == nil) == (shadowErr == nil) {
+		return
+	}
+
+	enforced := newAuditEvent(r, req, policyIDs, err)
+	candidate := newAuditEvent(r, sreq, shadowPolicyIDs, shadowErr)
+	c.shadowReporter(ShadowDisagreement{
+		Time:              enforced.Time,
+		RequestID:         enforced.RequestID,
+		Subject:           enforced.Subject,
+		Action:            enforced.Action,
+		Resource:          enforced.Resource,
+		EnforcedDecision:  enforced.Decision,
+		ShadowDecision:    candidate.Decision,
+		EnforcedPolicyIDs: enforced.PolicyIDs,
+		ShadowPolicyIDs:   candidate.PolicyIDs,
+	})
+}
+
+// ShadowLog reports the disagreements as warnings to a logrus logger
+func ShadowLog(l *logrus.Logger) ShadowReporter {
+	return func(d ShadowDisagreement) {
+		entry := l.WithFields(logrus.Fields{
+			"subject":             d.Subject,
+			"action":              d.Action,
+			"resource":            d.Resource,
+			"enforced_decision":   d.EnforcedDecision,
+			"shadow_decision":     d.ShadowDecision,
+			"enforced_policy_ids": d.EnforcedPolicyIDs,
+			"shadow_policy_ids":   d.ShadowPolicyIDs,
+		})
+		if d.RequestID != "" {
+			entry = entry.WithField("request_id", d.RequestID)
+		}
+		entry.Warnln("rbac shadow disagreement")
+	}
+}
+
+// ShadowLogZero reports the disagreements as warnings to a zerolog logger
+func ShadowLogZero(l zerolog.Logger) ShadowReporter {
+	return func(d ShadowDisagreement) {
+		ev := l.Warn()
+		if d.RequestID != "" {
+			ev = ev.Str("request_id", d.RequestID)
+		}
+		ev.Str("subject", d.Subject).
+			Str("action", d.Action).
+			Str("resource", d.Resource).
+			Str("enforced_decision", d.EnforcedDecision).
+			Str("shadow_decision", d.ShadowDecision).
+			Strs("enforced_policy_ids", d.EnforcedPolicyIDs).
+			Strs("shadow_policy_ids", d.ShadowPolicyIDs).
+			Msg("rbac shadow disagreement")
+	}
+}
@@ -0,0 +1,184 @@
+package gohttpmw
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ory/ladon"
+	manager "github.com/ory/ladon/manager/memory"
+	"github.com/rs/zerolog"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestRBACShadow(t *testing.T) {
+	fakeHandler := http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {},
+	)
+
+	enforcing := &ladon.Ladon{
+		Manager: manager.NewMemoryManager(),
+	}
+	_ = enforcing.Manager.Create(
+		&ladon.DefaultPolicy{
+			ID:        "current",
+			Subjects:  []string{"admin"},
+			Resources: []string{"https://pol.com/test"},
+			Actions:   []string{"GET"},
+			Effect:    ladon.AllowAccess,
+		},
+	)
+
+	candidate := &ladon.Ladon{
+		Manager: manager.NewMemoryManager(),
+	}
+	_ = candidate.Manager.Create(
+		&ladon.DefaultPolicy{
+			ID:        "candidate",
+			Subjects:  []string{"admin", "pollux"},
+			Resources: []string{"https://pol.com/test"},
+			Actions:   []string{"GET"},
+			Effect:    ladon.AllowAccess,
+		},
+	)
+
+	tests := []struct {
+		name             string
+		role             string
+		url              string
+		allowed          bool
+		expectedReported bool
+		expectedShadow   string
+	}{
+		{
+			name:    "both allow",
+			role:    "admin",
+			url:     "https://pol.com/test",
+			allowed: true,
+		},
+		{
+			name:    "both deny",
+			role:    "castor",
+			url:     "https://pol.com/test",
+			allowed: false,
+		},
+		{
+			name:             "shadow allows, not enforced",
+			role:             "pollux",
+			url:              "https://pol.com/test",
+			allowed:          false,
+			expectedReported: true,
+			expectedShadow:   ladon.AllowAccess,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reported []ShadowDisagreement
+			midWared := RequestID()(RBAC(
+				enforcing,
+				getRole,
+				WithShadowWarden(candidate, func(d ShadowDisagreement) {
+					reported = append(reported, d)
+				}),
+			)(fakeHandler))
+
+			ctx := context.WithValue(context.Background(), contextKeyRole, tt.role)
+			request := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			rr := httptest.NewRecorder()
+			midWared.ServeHTTP(rr, request.WithContext(ctx))
+
+			if tt.allowed && (rr.Code == http.StatusForbidden) ||
+				!tt.allowed && (rr.Code != http.StatusForbidden) {
+				t.Errorf("expected allowed %t, got %d", tt.allowed, rr.Code)
+			}
+
+			if !tt.expectedReported {
+				if len(reported) != 0 {
+					t.Errorf("expected no disagreement, got %+v", reported)
+				}
+				return
+			}
+
+			if len(reported) != 1 {
+				t.Fatalf("got %d disagreements instead of 1", len(reported))
+			}
+			d := reported[0]
+			if d.ShadowDecision != tt.expectedShadow ||
+				d.EnforcedDecision == tt.expectedShadow {
+				t.Errorf("wrong decisions in disagreement %+v", d)
+			}
+			if d.RequestID != rr.Header().Get("requestID") || d.RequestID == "" ||
+				d.Subject != tt.role {
+				t.Errorf("incomplete disagreement %+v", d)
+			}
+			if len(d.ShadowPolicyIDs) != 1 || d.ShadowPolicyIDs[0] != "candidate" {
+				t.Errorf("expected candidate policy id, got %v", d.ShadowPolicyIDs)
+			}
+		})
+	}
+}
+
+func TestRBACShadowNilReporter(t *testing.T) {
+	candidate := &ladon.Ladon{Manager: manager.NewMemoryManager()}
+	_ = candidate.Manager.Create(&ladon.DefaultPolicy{
+		ID:        "candidate",
+		Subjects:  []string{"pollux"},
+		Resources: []string{"https://pol.com/test"},
+		Actions:   []string{"GET"},
+		Effect:    ladon.AllowAccess,
+	})
+	midWared := RBAC(
+		&ladon.Ladon{Manager: manager.NewMemoryManager()},
+		getRole,
+		WithShadowWarden(candidate, nil),
+	)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+
+	ctx := context.WithValue(context.Background(), contextKeyRole, "pollux")
+	request := httptest.NewRequest(http.MethodGet, "https://pol.com/test", nil)
+	rr := httptest.NewRecorder()
+	midWared.ServeHTTP(rr, request.WithContext(ctx))
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("expected the enforced denial, got %d", rr.Code)
+	}
+}
+
+func TestShadowLog(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	ShadowLog(logger)(ShadowDisagreement{
+		RequestID:        "reqid",
+		EnforcedDecision: ladon.DenyAccess,
+		ShadowDecision:   ladon.AllowAccess,
+	})
+
+	if hook.LastEntry().Level != logrus.WarnLevel {
+		t.Errorf("expected warn level, got %v", hook.LastEntry().Level)
+	}
+	if hook.LastEntry().Data["request_id"] != "reqid" {
+		t.Errorf("missing request_id in shadow log")
+	}
+}
+
+func TestShadowLogZero(t *testing.T) {
+	out := &bytes.Buffer{}
+	ShadowLogZero(zerolog.New(out))(ShadowDisagreement{
+		RequestID:        "reqid",
+		EnforcedDecision: ladon.DenyAccess,
+		ShadowDecision:   ladon.AllowAccess,
+	})
+
+	logRes := make(map[string]interface{})
+	if err := json.Unmarshal(out.Bytes(), &logRes); err != nil {
+		t.Fatalf("error unmarshalling log %v", err)
+	}
+	if logRes["level"] != zerolog.WarnLevel.String() {
+		t.Errorf("expected warn level, got %v", logRes["level"])
+	}
+	if logRes["shadow_decision"] != ladon.AllowAccess {
+		t.Errorf("expected shadow decision, got %v", logRes["shadow_decision"])
+	}
+}
@@ -0,0 +1,338 @@
+package gohttpmw
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ContextKeyClientInfo stores the client info resolved by RealIP
+const ContextKeyClientInfo = ContextKey("clientInfo")
+
+// ClientInfo describes the client as seen by the first trusted proxy
+type ClientInfo struct {
+	IP     string
+	Scheme string
+	Host   string
+}
+
+// TrustedProxies is the set of networks allowed to forward
+// the client info in their headers
+type TrustedProxies struct {
+	networks *ipTrie
+}
+
+// NewTrustedProxies parses the CIDRs of the trusted proxies,
+// single addresses are accepted as well
+func NewTrustedProxies(cidrs ...string) (*TrustedProxies, error) {
+	tp := &TrustedProxies{networks: newIPTrie()}
+	for _, cidr := range cidrs {
+		prefix, err := parsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("realip: %q: %w", cidr, err)
+		}
+		tp.networks.insert(prefix)
+	}
+
+	return tp, nil
+}
+
+// Trusted tells if ip belongs to a trusted proxy
+func (tp *TrustedProxies) Trusted(ip netip.Addr) bool {
+	return tp.networks.contains(ip)
+}
+
+// RealIPHeader is the header the trusted proxies set the client info in
+type RealIPHeader string
+
+// The headers supported by RealIP
+const (
+	// RealIPXForwardedFor is X-Forwarded-For, with X-Forwarded-Proto
+	// and X-Forwarded-Host
+	RealIPXForwardedFor RealIPHeader = "X-Forwarded-For"
+	// RealIPForwarded is the RFC 7239 Forwarded header
+	RealIPForwarded RealIPHeader = "Forwarded"
+	// RealIPXRealIP is X-Real-IP, a single address
+	RealIPXRealIP RealIPHeader = "X-Real-IP"
+)
+
+// ParseRealIPHeader parses the name of a RealIPHeader, case insensitively
+func ParseRealIPHeader(s string) (RealIPHeader, error) {
+	for _, h := range []RealIPHeader{RealIPXForwardedFor, RealIPForwarded, RealIPXRealIP} {
+		if strings.EqualFold(s, string(h)) {
+			return h, nil
+		}
+	}
+
+	return "", fmt.Errorf("realip: unsupported header %q", s)
+}
+
+// RealIPOption configures RealIP
+type RealIPOption func(*realIPConfig)
+
+type realIPConfig struct {
+	header RealIPHeader
+}
+
+// WithRealIPHeader sets the header the client info is read from,
+// X-Forwarded-For by default
+func WithRealIPHeader(h RealIPHeader) RealIPOption {
+	return func(c *realIPConfig) {
+		c.header = h
+	}
+}
+
+// RealIP resolves the client ip, scheme and host from a single header,
+// X-Forwarded-For by default, when the request comes from a trusted proxy.
+// The other forwarding headers are ignored, as the proxies usually pass
+// them through from the client unchanged. The forwarding chain is walked
+// back from the nearest proxy, the client is the first hop that is not
+// trusted. The loggers, the rate limiter, the tracing and RBAC use
+// the resolved client info
+func RealIP(tp *TrustedProxies, opts ...RealIPOption) func(http.Handler) http.Handler {
+	cfg := realIPConfig{header: RealIPXForwardedFor}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			info := tp.resolve(r, cfg.header)
+			*r = *r.WithContext(
+				context.WithValue(r.Context(), ContextKeyClientInfo, info),
+			)
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+// GetClientInfo retrieves the client info resolved by RealIP
+func GetClientInfo(ctx context.Context) *ClientInfo {
+	if info, ok := ctx.Value(ContextKeyClientInfo).(*ClientInfo); ok {
+		return info
+	}
+
+	return nil
+}
+
+// forwardedHop is one proxy hop, with what it saw of the previous one
+type forwardedHop struct {
+	ip    netip.Addr
+	proto string
+	host  string
+}
+
+func (tp *TrustedProxies) resolve(r *http.Request, header RealIPHeader) *ClientInfo {
+	info := &ClientInfo{
+		IP:     remoteAddrIP(r),
+		Scheme: "http",
+		Host:   r.Host,
+	}
+	if r.TLS != nil {
+		info.Scheme = "https"
+	}
+
+	peer, err := netip.ParseAddr(info.IP)
+	if err != nil || !tp.Trusted(peer) {
+		return info
+	}
+
+	// Never fall back on another header, the client could set it
+	var hops []forwardedHop
+	switch header {
+	case RealIPForwarded:
+		hops = parseForwarded(r.Header.Values("Forwarded"))
+	case RealIPXForwardedFor:
+		hops = parseXForwarded(r.Header)
+	case RealIPXRealIP:
+		if v := r.Header.Get("X-Real-IP"); v != "" {
+			ip, err := netip.ParseAddr(strings.TrimSpace(v))
+			if err == nil {
+				hops = []forwardedHop{{ip: ip}}
+			}
+		}
+	}
+
+	// Walk back from the nearest proxy, until the first untrusted hop
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := hops[i]
+		if !hop.ip.IsValid() {
+			// Obfuscated or malformed, nothing further can be trusted
+			break
+		}
+		info.IP = hop.ip.Unmap().String()
+		if hop.proto != "" {
+			info.Scheme = hop.proto
+		}
+		if hop.host != "" {
+			info.Host = hop.host
+		}
+		if !tp.Trusted(hop.ip) {
+			break
+		}
+	}
+
+	return info
+}
+
+// parseForwarded parses the RFC 7239 Forwarded headers
+func parseForwarded(values []string) []forwardedHop {
+	var hops []forwardedHop
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			var hop forwardedHop
+			for _, pair := range strings.Split(element, ";") {
+				k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok {
+					continue
+				}
+				v = strings.Trim(v, `"`)
+				switch strings.ToLower(k) {
+				case "for":
+					hop.ip = parseForwardedNode(v)
+				case "proto":
+					hop.proto = strings.ToLower(v)
+				case "host":
+					hop.host = v
+				}
+			}
+			hops = append(hops, hop)
+		}
+	}
+
+	return hops
+}
+
+// parseForwardedNode parses an ip with an optional port,
+// ipv6 addresses are bracketed
+func parseForwardedNode(node string) netip.Addr {
+	if strings.HasPrefix(node, "[") {
+		end := strings.IndexByte(node, ']')
+		if end < 0 {
+			return netip.Addr{}
+		}
+		node = node[1:end]
+	} else if host, _, err := net.SplitHostPort(node); err == nil {
+		node = host
+	}
+	ip, err := netip.ParseAddr(node)
+	if err != nil {
+		return netip.Addr{}
+	}
+
+	return ip
+}
+
+// parseXForwarded parses X-Forwarded-For, the proto and host
+// are matched to the hops when each proxy added its own
+func parseXForwarded(header http.Header) []forwardedHop {
+	ips := splitHeaderList(header.Values("X-Forwarded-For"))
+	protos := splitHeaderList(header.Values("X-Forwarded-Proto"))
+	hosts := splitHeaderList(header.Values("X-Forwarded-Host"))
+
+	hops := make([]forwardedHop, len(ips))
+	for i, ip := range ips {
+		hops[i].ip = parseForwardedNode(ip)
+		switch len(protos) {
+		case len(ips):
+			hops[i].proto = strings.ToLower(protos[i])
+		case 1:
+			hops[i].proto = strings.ToLower(protos[0])
+		}
+		switch len(hosts) {
+		case len(ips):
+			hops[i].host = hosts[i]
+		case 1:
+			hops[i].host = hosts[0]
+		}
+	}
+
+	return hops
+}
+
+func splitHeaderList(values []string) []string {
+	var list []string
+	for _, value := range values {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				list = append(list, v)
+			}
+		}
+	}
+
+	return list
+}
+
+// parsePrefix parses a CIDR or a single address
+func parsePrefix(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		return prefix.Masked(), nil
+	}
+	ip, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	ip = ip.Unmap()
+
+	return netip.PrefixFrom(ip, ip.BitLen()), nil
+}
+
+// clientIP is the ip resolved by RealIP, or else the peer ip
+func clientIP(r *http.Request) string {
+	if info := GetClientInfo(r.Context()); info != nil {
+		return info.IP
+	}
+
+	return remoteAddrIP(r)
+}
+
+// logRemoteAddr is the ip resolved by RealIP, or else the peer address
+// with its port as the loggers always logged it
+func logRemoteAddr(r *http.Request) string {
+	if info := GetClientInfo(r.Context()); info != nil {
+		return info.IP
+	}
+
+	return r.RemoteAddr
+}
+
+// requestScheme is the scheme resolved by RealIP, or else the peer scheme
+func requestScheme(r *http.Request) string {
+	if info := GetClientInfo(r.Context()); info != nil {
+		return info.Scheme
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+
+	return "http"
+}
+
+// requestHost is the host resolved by RealIP, or else the request host
+func requestHost(r *http.Request) string {
+	if info := GetClientInfo(r.Context()); info != nil {
+		return info.Host
+	}
+
+	return r.Host
+}
+
+func remoteAddrIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
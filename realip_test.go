@@ -0,0 +1,219 @@
+package gohttpmw
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestNewTrustedProxies(t *testing.T) {
+	if _, err := NewTrustedProxies("10.0.0.0/8", "::1", "fd00::/8"); err != nil {
+		t.Errorf("expected valid proxies, got %v", err)
+	}
+	if _, err := NewTrustedProxies("10.0.0.0/33"); err == nil {
+		t.Errorf("expected an error for an invalid cidr")
+	}
+	if _, err := NewTrustedProxies("proxy.local"); err == nil {
+		t.Errorf("expected an error for a host name")
+	}
+
+	tp, err := NewTrustedProxies("::ffff:10.0.0.0/104")
+	if err != nil {
+		t.Fatalf("expected a valid mapped cidr, got %v", err)
+	}
+	if !tp.Trusted(netip.MustParseAddr("10.0.0.5")) || tp.Trusted(netip.MustParseAddr("11.0.0.5")) {
+		t.Errorf("expected the mapped cidr to hold 10.0.0.0/8")
+	}
+}
+
+func TestRealIP(t *testing.T) {
+	tp, err := NewTrustedProxies("10.0.0.0/8", "fd00::/8")
+	if err != nil {
+		t.Fatalf("NewTrustedProxies: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		tls        bool
+		header     RealIPHeader
+		headers    map[string]string
+		expected   ClientInfo
+	}{
+		{
+			name:       "direct client",
+			remoteAddr: "203.0.113.1:1234",
+			expected:   ClientInfo{IP: "203.0.113.1", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:       "untrusted peer spoofing headers",
+			remoteAddr: "203.0.113.1:1234",
+			tls:        true,
+			headers: map[string]string{
+				"X-Forwarded-For":   "198.51.100.1",
+				"X-Forwarded-Proto": "http",
+			},
+			expected: ClientInfo{IP: "203.0.113.1", Scheme: "https", Host: "example.com"},
+		},
+		{
+			name:       "x-forwarded-for",
+			remoteAddr: "10.0.0.1:1234",
+			headers: map[string]string{
+				"X-Forwarded-For":   "198.51.100.1, 10.0.0.2",
+				"X-Forwarded-Proto": "https",
+				"X-Forwarded-Host":  "api.example.com",
+			},
+			expected: ClientInfo{IP: "198.51.100.1", Scheme: "https", Host: "api.example.com"},
+		},
+		{
+			name:       "x-forwarded-for with spoofed first hop",
+			remoteAddr: "10.0.0.1:1234",
+			headers: map[string]string{
+				"X-Forwarded-For": "1.2.3.4, 198.51.100.1",
+			},
+			expected: ClientInfo{IP: "198.51.100.1", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:       "x-forwarded-for ignores a spoofed forwarded",
+			remoteAddr: "10.0.0.5:1234",
+			headers: map[string]string{
+				"X-Forwarded-For": "203.0.113.9",
+				"Forwarded":       "for=192.168.1.10",
+			},
+			expected: ClientInfo{IP: "203.0.113.9", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:       "forwarded ignores x-forwarded-for",
+			remoteAddr: "10.0.0.5:1234",
+			header:     RealIPForwarded,
+			headers: map[string]string{
+				"X-Forwarded-For": "192.168.1.10",
+			},
+			expected: ClientInfo{IP: "10.0.0.5", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:       "forwarded",
+			remoteAddr: "10.0.0.1:1234",
+			header:     RealIPForwarded,
+			headers: map[string]string{
+				"Forwarded": `for="[2001:db8::1]:4711";proto=https;host=api.example.com, for=10.0.0.2`,
+			},
+			expected: ClientInfo{IP: "2001:db8::1", Scheme: "https", Host: "api.example.com"},
+		},
+		{
+			name:       "forwarded with obfuscated hop",
+			remoteAddr: "10.0.0.1:1234",
+			header:     RealIPForwarded,
+			headers: map[string]string{
+				"Forwarded": "for=198.51.100.1, for=_hidden, for=10.0.0.2",
+			},
+			expected: ClientInfo{IP: "10.0.0.2", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:       "x-real-ip from ipv6 proxy",
+			remoteAddr: "[fd00::1]:1234",
+			header:     RealIPXRealIP,
+			headers: map[string]string{
+				"X-Real-IP": "198.51.100.1",
+			},
+			expected: ClientInfo{IP: "198.51.100.1", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:       "trusted proxy without headers",
+			remoteAddr: "10.0.0.1:1234",
+			expected:   ClientInfo{IP: "10.0.0.1", Scheme: "http", Host: "example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var info *ClientInfo
+			fakeHandler := http.HandlerFunc(
+				func(w http.ResponseWriter, req *http.Request) {
+					info = GetClientInfo(req.Context())
+				},
+			)
+			rr := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, `http://example.com/`, nil)
+			request.RemoteAddr = tt.remoteAddr
+			if tt.tls {
+				request.TLS = &tls.ConnectionState{}
+			}
+			for k, v := range tt.headers {
+				request.Header.Set(k, v)
+			}
+			var opts []RealIPOption
+			if tt.header != "" {
+				opts = append(opts, WithRealIPHeader(tt.header))
+			}
+			RealIP(tp, opts...)(fakeHandler).ServeHTTP(rr, request)
+
+			if info == nil {
+				t.Fatalf("expected client info in context")
+			}
+			if *info != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, *info)
+			}
+		})
+	}
+}
+
+func TestParseRealIPHeader(t *testing.T) {
+	for s, expected := range map[string]RealIPHeader{
+		"x-forwarded-for": RealIPXForwardedFor,
+		"Forwarded":       RealIPForwarded,
+		"X-REAL-IP":       RealIPXRealIP,
+	} {
+		if h, err := ParseRealIPHeader(s); err != nil || h != expected {
+			t.Errorf("%s: expected %s, got %s %v", s, expected, h, err)
+		}
+	}
+	if _, err := ParseRealIPHeader("X-Client-IP"); err == nil {
+		t.Errorf("expected an error for an unsupported header")
+	}
+}
+
+func TestRealIPUsed(t *testing.T) {
+	tp, err := NewTrustedProxies("10.0.0.0/8")
+	if err != nil {
+		t.Fatalf("NewTrustedProxies: %v", err)
+	}
+
+	var (
+		rateLimitKey string
+		rbacCtx      map[string]interface{}
+	)
+	fakeHandler := http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			rateLimitKey = KeyByRemoteIP(req)
+			rbacCtx = rbacContext(req)
+		},
+	)
+	logger, hook := test.NewNullLogger()
+	midWared := Logger(logger)(RealIP(tp)(fakeHandler))
+
+	rr := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, `/`, nil)
+	request.RemoteAddr = "10.0.0.1:1234"
+	request.Header.Set("X-Forwarded-For", "198.51.100.1")
+	request.Header.Set("X-Forwarded-Proto", "https")
+	midWared.ServeHTTP(rr, request)
+
+	data := hook.LastEntry().Data
+	if data["remote_addr"] != "198.51.100.1" {
+		t.Errorf("expected remote_addr 198.51.100.1, got %v", data["remote_addr"])
+	}
+	if data["http_scheme"] != "https" {
+		t.Errorf("expected http_scheme https, got %v", data["http_scheme"])
+	}
+	if rateLimitKey != "ip:198.51.100.1" {
+		t.Errorf("expected rate limit key ip:198.51.100.1, got %s", rateLimitKey)
+	}
+	if rbacCtx["remoteIPAddress"] != "198.51.100.1" {
+		t.Errorf("expected rbac remoteIPAddress 198.51.100.1, got %v", rbacCtx["remoteIPAddress"])
+	}
+}
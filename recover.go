@@ -0,0 +1,42 @@
+package gohttpmw
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// PanicError is recorded as the request error of the requests
+// whose handler panicked
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Recover turns the panics of the handler into a 500, the panic is
+// recorded as the request error so it should run inside the logger
+// for the panic to be logged. http.ErrAbortHandler is not recovered
+func Recover() func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+
+				SetRequestError(r, &PanicError{Value: rec, Stack: debug.Stack()})
+				w.WriteHeader(http.StatusInternalServerError)
+			}()
+
+			h.ServeHTTP(w, r)
+		})
+	}
+}
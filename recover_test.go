@@ -0,0 +1,104 @@
+package gohttpmw
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestRecover(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	fakeHandler := http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			panic("fish")
+		},
+	)
+	midWared := Logger(logger)(Recover()(fakeHandler))
+
+	rr := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, `/`, nil)
+	midWared.ServeHTTP(rr, request)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", rr.Code)
+	}
+	panicErr, ok := GetRequestError(request.Context()).(*PanicError)
+	if !ok {
+		t.Fatalf("expected a PanicError, got %v", GetRequestError(request.Context()))
+	}
+	if panicErr.Value != "fish" || len(panicErr.Stack) == 0 {
+		t.Errorf("expected the panic value and stack, got %v", panicErr)
+	}
+	if hook.LastEntry().Level != logrus.ErrorLevel || hook.LastEntry().Message != "panic: fish" {
+		t.Errorf(
+			"expected the panic to be logged as error, got %s %q",
+			hook.LastEntry().Level, hook.LastEntry().Message,
+		)
+	}
+	if stack, _ := hook.LastEntry().Data["stack"].(string); stack != string(panicErr.Stack) {
+		t.Errorf("expected the panic stack to be logged, got %q", stack)
+	}
+}
+
+func TestRecoverStackLogged(t *testing.T) {
+	tests := []struct {
+		name   string
+		logger func(out *bytes.Buffer) Middleware
+	}{
+		{
+			name: "slog",
+			logger: func(out *bytes.Buffer) Middleware {
+				return LoggerSlog(slog.New(slog.NewJSONHandler(out, nil)))
+			},
+		},
+		{
+			name: "zerolog",
+			logger: func(out *bytes.Buffer) Middleware {
+				return LoggerZero(zerolog.New(out))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			handler := NewChain(tt.logger(out), Recover()).ThenFunc(
+				func(w http.ResponseWriter, r *http.Request) { panic("fish") },
+			)
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+			var logRes map[string]interface{}
+			if err := json.Unmarshal(out.Bytes(), &logRes); err != nil {
+				t.Fatalf("error unmarshalling log %v", err)
+			}
+			if stack, _ := logRes["stack"].(string); !strings.Contains(stack, "goroutine") {
+				t.Errorf("expected the panic stack to be logged, got %q", stack)
+			}
+		})
+	}
+}
+
+func TestRecoverAbortHandler(t *testing.T) {
+	fakeHandler := http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			panic(http.ErrAbortHandler)
+		},
+	)
+
+	defer func() {
+		if rec := recover(); rec != http.ErrAbortHandler {
+			t.Errorf("expected http.ErrAbortHandler to be re-panicked, got %v", rec)
+		}
+	}()
+	Recover()(fakeHandler).ServeHTTP(
+		httptest.NewRecorder(),
+		httptest.NewRequest(http.MethodGet, `/`, nil),
+	)
+}
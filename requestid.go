@@ -17,8 +17,8 @@ const (
 func RequestID() func(http.Handler) http.Handler {
 	return func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			requestID := xid.New()
-			w.Header().Add("requestID", requestID.String())
+			requestID := xid.New().String()
+			w.Header().Add("requestID", requestID)
 			ctx := context.WithValue(
 				r.Context(),
 				ContextKeyRequestID,
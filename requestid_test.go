@@ -13,7 +13,13 @@ func TestRequestID(t *testing.T) {
 	fakeHandler := http.HandlerFunc(
 		func(w http.ResponseWriter, req *http.Request) {},
 	)
-	midWared := RequestID()(fakeHandler)
+	var ctxReqID string
+	midWared := RequestID()(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			ctxReqID = GetRequestID(req.Context())
+			fakeHandler.ServeHTTP(w, req)
+		},
+	))
 	rr := httptest.NewRecorder()
 	request := httptest.NewRequest(http.MethodGet, `/`, nil)
 
@@ -21,6 +27,9 @@ func TestRequestID(t *testing.T) {
 	if rr.Header().Get("requestID") == "" {
 		t.Errorf("expected a requestid, got nothing")
 	}
+	if ctxReqID != rr.Header().Get("requestID") {
+		t.Errorf("expected the context requestid %q, got %q", rr.Header().Get("requestID"), ctxReqID)
+	}
 }
 
 func TestGetRequestID(t *testing.T) {
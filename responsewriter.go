@@ -15,10 +15,11 @@ func (w *augmentedResponseWriter) WriteHeader(httpStatus int) {
 	w.httpStatus = httpStatus
 }
 
-// Write will not only write b to w but also save the byte length in the struct
+// Write will not only write b to w but also add the byte length
+// to the total in the struct
 func (w *augmentedResponseWriter) Write(b []byte) (int, error) {
 	n, err := w.ResponseWriter.Write(b)
-	w.length = n
+	w.length += n
 
 	return n, err
 }
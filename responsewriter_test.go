@@ -18,3 +18,16 @@ func Test_augmentedResponseWriter_Write(t *testing.T) {
 	}
 
 }
+
+func Test_augmentedResponseWriter_MultipleWrites(t *testing.T) {
+	rr := httptest.NewRecorder()
+	arw := newAugmentedResponseWriter(rr)
+	_, _ = arw.Write([]byte("test"))
+	_, _ = arw.Write([]byte("test"))
+	if arw.length != 8 {
+		t.Errorf("augmentedResponseWriter.Write() length %d instead of %d",
+			arw.length, 8,
+		)
+		return
+	}
+}
@@ -0,0 +1,98 @@
+package gohttpmw
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrPrincipalMissing is recorded when a middleware needs an authenticated
+// principal but none was found in the context
+var ErrPrincipalMissing = errors.New("no authenticated principal")
+
+// ScopeMode tells if all the required scopes are needed or any of them
+type ScopeMode int
+
+// Scope modes
+const (
+	AllScopes ScopeMode = iota
+	AnyScope
+)
+
+// InsufficientScopeError is recorded as the request error
+// when the principal lacks the required scopes
+type InsufficientScopeError struct {
+	Missing []string
+}
+
+func (e *InsufficientScopeError) Error() string {
+	return fmt.Sprintf("insufficient scope, missing %s", strings.Join(e.Missing, " "))
+}
+
+// RequireScopes allows the request only if the principal has all the scopes,
+// otherwise it gets a 403 with an insufficient_scope challenge
+func RequireScopes(scopes ...string) func(http.Handler) http.Handler {
+	return RequireMethodScopes(map[string][]string{"*": scopes}, AllScopes)
+}
+
+// RequireAnyScope allows the request only if the principal has
+// one of the scopes, otherwise it gets a 403 with an insufficient_scope
+// challenge
+func RequireAnyScope(scopes ...string) func(http.Handler) http.Handler {
+	return RequireMethodScopes(map[string][]string{"*": scopes}, AnyScope)
+}
+
+// RequireMethodScopes checks the scopes required for the method
+// of the request, the "*" key applies to the methods not listed
+// and methods without scopes are allowed
+func RequireMethodScopes(
+	scopes map[string][]string,
+	mode ScopeMode,
+) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			required, ok := scopes[r.Method]
+			if !ok {
+				required = scopes["*"]
+			}
+			if len(required) == 0 {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			p := GetPrincipal(r.Context())
+			if p == nil {
+				SetRequestError(r, ErrPrincipalMissing)
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			if missing := missingScopes(p, required, mode); len(missing) > 0 {
+				SetRequestError(r, &InsufficientScopeError{Missing: missing})
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(
+					`Bearer error="insufficient_scope", scope=%q`,
+					strings.Join(required, " "),
+				))
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			h.ServeHTTP(w, r)
+		})
+	}
+}
+
+func missingScopes(p *Principal, required []string, mode ScopeMode) []string {
+	var missing []string
+	for _, scope := range required {
+		if !p.HasScope(scope) {
+			missing = append(missing, scope)
+		} else if mode == AnyScope {
+			return nil
+		}
+	}
+
+	return missing
+}
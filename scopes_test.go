@@ -0,0 +1,137 @@
+package gohttpmw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRequireScopes(t *testing.T) {
+	tests := []struct {
+		name            string
+		mw              func(http.Handler) http.Handler
+		method          string
+		principal       *Principal
+		expectedStatus  int
+		expectedMissing []string
+	}{
+		{
+			name:           "all scopes present",
+			mw:             RequireScopes("read", "write"),
+			method:         http.MethodGet,
+			principal:      &Principal{Scopes: []string{"write", "read"}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:            "one scope missing",
+			mw:              RequireScopes("read", "write"),
+			method:          http.MethodGet,
+			principal:       &Principal{Scopes: []string{"read"}},
+			expectedStatus:  http.StatusForbidden,
+			expectedMissing: []string{"write"},
+		},
+		{
+			name:           "any scope present",
+			mw:             RequireAnyScope("read", "write"),
+			method:         http.MethodGet,
+			principal:      &Principal{Scopes: []string{"write"}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:            "any scope missing",
+			mw:              RequireAnyScope("read", "write"),
+			method:          http.MethodGet,
+			principal:       &Principal{Scopes: []string{"admin"}},
+			expectedStatus:  http.StatusForbidden,
+			expectedMissing: []string{"read", "write"},
+		},
+		{
+			name: "method scopes",
+			mw: RequireMethodScopes(map[string][]string{
+				http.MethodPost: {"write"},
+				"*":             {"read"},
+			}, AllScopes),
+			method:          http.MethodPost,
+			principal:       &Principal{Scopes: []string{"read"}},
+			expectedStatus:  http.StatusForbidden,
+			expectedMissing: []string{"write"},
+		},
+		{
+			name: "method scopes default",
+			mw: RequireMethodScopes(map[string][]string{
+				http.MethodPost: {"write"},
+				"*":             {"read"},
+			}, AllScopes),
+			method:         http.MethodGet,
+			principal:      &Principal{Scopes: []string{"read"}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "method without scopes",
+			mw: RequireMethodScopes(map[string][]string{
+				http.MethodPost: {"write"},
+			}, AllScopes),
+			method:         http.MethodGet,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "no principal",
+			mw:             RequireScopes("read"),
+			method:         http.MethodGet,
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeHandler := http.HandlerFunc(
+				func(w http.ResponseWriter, req *http.Request) {},
+			)
+			midWared := tt.mw(fakeHandler)
+			rr := httptest.NewRecorder()
+			request := httptest.NewRequest(tt.method, `/`, nil)
+			if tt.principal != nil {
+				*request = *request.WithContext(
+					WithPrincipal(request.Context(), tt.principal),
+				)
+			}
+			midWared.ServeHTTP(rr, request)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+
+			reqErr := GetRequestError(request.Context())
+			if tt.expectedStatus == http.StatusUnauthorized {
+				if reqErr != ErrPrincipalMissing {
+					t.Errorf("expected %v, got %v", ErrPrincipalMissing, reqErr)
+				}
+				return
+			}
+			if tt.expectedMissing == nil {
+				if reqErr != nil {
+					t.Errorf("expected no request error, got %v", reqErr)
+				}
+				return
+			}
+
+			scopeErr, ok := reqErr.(*InsufficientScopeError)
+			if !ok {
+				t.Fatalf("expected an InsufficientScopeError, got %v", reqErr)
+			}
+			if !reflect.DeepEqual(scopeErr.Missing, tt.expectedMissing) {
+				t.Errorf(
+					"expected missing scopes %v, got %v",
+					tt.expectedMissing, scopeErr.Missing,
+				)
+			}
+			if got := rr.Header().Get("WWW-Authenticate"); !strings.HasPrefix(
+				got, `Bearer error="insufficient_scope"`,
+			) {
+				t.Errorf("expected insufficient_scope challenge, got %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package gohttpmw
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/rs/zerolog"
+	"github.com/sirupsen/logrus"
+)
+
+// slogZeroHandler is a slog.Handler logging to a zerolog logger
+type slogZeroHandler struct {
+	l      zerolog.Logger
+	prefix string
+}
+
+func (h *slogZeroHandler) Enabled(_ context.Context, level slog.Level) bool {
+	lvl := zerologLevel(level)
+
+	return lvl >= h.l.GetLevel() && lvl >= zerolog.GlobalLevel()
+}
+
+func (h *slogZeroHandler) Handle(_ context.Context, r slog.Record) error {
+	fields := make([]interface{}, 0, 2*r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendSlogAttr(fields, h.prefix, a)
+		return true
+	})
+	h.l.WithLevel(zerologLevel(r.Level)).Fields(fields).Msg(r.Message)
+
+	return nil
+}
+
+func (h *slogZeroHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var fields []interface{}
+	for _, a := range attrs {
+		fields = appendSlogAttr(fields, h.prefix, a)
+	}
+
+	return &slogZeroHandler{l: h.l.With().Fields(fields).Logger(), prefix: h.prefix}
+}
+
+func (h *slogZeroHandler) WithGroup(name string) slog.Handler {
+	return &slogZeroHandler{l: h.l, prefix: h.prefix + name + "."}
+}
+
+func zerologLevel(level slog.Level) zerolog.Level {
+	switch {
+	case level >= slog.LevelError:
+		return zerolog.ErrorLevel
+	case level >= slog.LevelWarn:
+		return zerolog.WarnLevel
+	case level >= slog.LevelInfo:
+		return zerolog.InfoLevel
+	case level >= slog.LevelDebug:
+		return zerolog.DebugLevel
+	}
+
+	return zerolog.TraceLevel
+}
+
+// slogLogrusHandler is a slog.Handler logging to a logrus entry
+type slogLogrusHandler struct {
+	e      *logrus.Entry
+	prefix string
+}
+
+func (h *slogLogrusHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.e.Logger.IsLevelEnabled(logrusLevel(level))
+}
+
+func (h *slogLogrusHandler) Handle(_ context.Context, r slog.Record) error {
+	fields := make(logrus.Fields, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		addSlogAttrFields(fields, h.prefix, a)
+		return true
+	})
+	h.e.WithTime(r.Time).WithFields(fields).Log(logrusLevel(r.Level), r.Message)
+
+	return nil
+}
+
+func (h *slogLogrusHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	fields := make(logrus.Fields, len(attrs))
+	for _, a := range attrs {
+		addSlogAttrFields(fields, h.prefix, a)
+	}
+
+	return &slogLogrusHandler{e: h.e.WithFields(fields), prefix: h.prefix}
+}
+
+func (h *slogLogrusHandler) WithGroup(name string) slog.Handler {
+	return &slogLogrusHandler{e: h.e, prefix: h.prefix + name + "."}
+}
+
+func logrusLevel(level slog.Level) logrus.Level {
+	switch {
+	case level >= slog.LevelError:
+		return logrus.ErrorLevel
+	case level >= slog.LevelWarn:
+		return logrus.WarnLevel
+	case level >= slog.LevelInfo:
+		return logrus.InfoLevel
+	case level >= slog.LevelDebug:
+		return logrus.DebugLevel
+	}
+
+	return logrus.TraceLevel
+}
+
+// appendSlogAttr appends the key and value of a to fields,
+// the groups are flattened into keys such as group.key
+func appendSlogAttr(fields []interface{}, prefix string, a slog.Attr) []interface{} {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+	if a.Value.Kind() != slog.KindGroup {
+		return append(fields, prefix+a.Key, a.Value.Any())
+	}
+	if a.Key != "" {
+		prefix += a.Key + "."
+	}
+	for _, ga := range a.Value.Group() {
+		fields = appendSlogAttr(fields, prefix, ga)
+	}
+
+	return fields
+}
+
+func addSlogAttrFields(fields logrus.Fields, prefix string, a slog.Attr) {
+	kvs := appendSlogAttr(nil, prefix, a)
+	for i := 0; i < len(kvs); i += 2 {
+		fields[kvs[i].(string)] = kvs[i+1]
+	}
+}
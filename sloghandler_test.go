@@ -0,0 +1,72 @@
+package gohttpmw
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/sirupsen/logrus"
+)
+
+func TestSlogHandlers(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(*bytes.Buffer) slog.Handler
+	}{
+		{
+			name: "zerolog",
+			handler: func(out *bytes.Buffer) slog.Handler {
+				return &slogZeroHandler{l: zerolog.New(out).Level(zerolog.InfoLevel)}
+			},
+		},
+		{
+			name: "logrus",
+			handler: func(out *bytes.Buffer) slog.Handler {
+				l := logrus.New()
+				l.Out = out
+				l.SetFormatter(&logrus.JSONFormatter{})
+				return &slogLogrusHandler{e: logrus.NewEntry(l)}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			l := slog.New(tt.handler(out)).
+				With("service", "fish").
+				WithGroup("req")
+
+			l.Debug("hidden")
+			if out.Len() != 0 {
+				t.Fatalf("expected debug to be disabled, got %s", out.String())
+			}
+
+			l.Warn("caught", "size", 3, slog.Group("bait", "kind", "worm"))
+			var logRes map[string]interface{}
+			if err := json.Unmarshal(out.Bytes(), &logRes); err != nil {
+				t.Fatalf("error unmarshalling log %v", err)
+			}
+			expected := map[string]interface{}{
+				"level":         "warning",
+				"service":       "fish",
+				"req.size":      float64(3),
+				"req.bait.kind": "worm",
+				"msg":           "caught",
+				"message":       "caught",
+			}
+			if tt.name == "zerolog" {
+				expected["level"] = "warn"
+				delete(expected, "msg")
+			} else {
+				delete(expected, "message")
+			}
+			for k, v := range expected {
+				if logRes[k] != v {
+					t.Errorf("expected %s %v, got %v", k, v, logRes[k])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package gohttpmw
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrRequestTimeout is recorded as the request error of the requests
+// that didn't start responding before their deadline
+var ErrRequestTimeout = errors.New("request timed out")
+
+// TimeoutOption configures the Timeout middleware
+type TimeoutOption func(*timeoutConfig)
+
+type timeoutConfig struct {
+	status   int
+	duration func(*http.Request) time.Duration
+}
+
+// WithTimeoutStatus sets the status written on timeout,
+// 503 by default, 504 is also common behind gateways
+func WithTimeoutStatus(status int) TimeoutOption {
+	return func(c *timeoutConfig) {
+		c.status = status
+	}
+}
+
+// WithRouteTimeout gives a duration per request, returning 0 keeps
+// the default duration and a negative duration disables the timeout
+func WithRouteTimeout(f func(*http.Request) time.Duration) TimeoutOption {
+	return func(c *timeoutConfig) {
+		c.duration = f
+	}
+}
+
+// Timeout sets a deadline of d on the request context. If the handler hasn't
+// started responding by then, the timeout status is written, the later
+// writes of the handler are discarded and the timeout is recorded as the
+// request error. The handler keeps running in the request goroutine,
+// so the response only completes once it returns: it should give up
+// when its context is done
+func Timeout(d time.Duration, opts ...TimeoutOption) func(http.Handler) http.Handler {
+	cfg := timeoutConfig{status: http.StatusServiceUnavailable}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			timeout := d
+			if cfg.duration != nil {
+				if routeTimeout := cfg.duration(r); routeTimeout != 0 {
+					timeout = routeTimeout
+				}
+			}
+			if timeout <= 0 {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			tw := &timeoutWriter{
+				w:      w,
+				h:      make(http.Header),
+				ctx:    ctx,
+				status: cfg.status,
+			}
+			timer := time.AfterFunc(timeout, tw.timeout)
+			defer timer.Stop()
+
+			// We don't want to lose the reference to the Request
+			*r = *r.WithContext(ctx)
+			h.ServeHTTP(tw, r)
+
+			if tw.finish() {
+				SetRequestError(r, ErrRequestTimeout)
+			}
+		})
+	}
+}
+
+// timeoutWriter serializes the writes of the handler and of the timer,
+// and discards the handler's writes once the timeout response is written.
+// The handler gets its own header map, copied when it starts responding,
+// so that the timer never touches a map the handler may be writing to
+type timeoutWriter struct {
+	w      http.ResponseWriter
+	h      http.Header
+	ctx    context.Context
+	status int
+
+	mu          sync.Mutex
+	wroteHeader bool
+	timedOut    bool
+	done        bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.h
+}
+
+func (tw *timeoutWriter) WriteHeader(httpStatus int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut || tw.wroteHeader || tw.expired() {
+		return
+	}
+	tw.writeHeader(httpStatus)
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut || (!tw.wroteHeader && tw.expired()) {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !tw.wroteHeader {
+		tw.writeHeader(http.StatusOK)
+	}
+
+	return tw.w.Write(b)
+}
+
+func (tw *timeoutWriter) writeHeader(httpStatus int) {
+	tw.wroteHeader = true
+	dst := tw.w.Header()
+	for k, v := range tw.h {
+		dst[k] = v
+	}
+	tw.w.WriteHeader(httpStatus)
+}
+
+// timeout writes the timeout response unless the handler
+// already started responding or returned
+func (tw *timeoutWriter) timeout() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.done || tw.wroteHeader {
+		return
+	}
+	tw.writeTimeout()
+}
+
+// expired writes the timeout response if the deadline passed, the handler
+// may see its context done before the timer fired
+func (tw *timeoutWriter) expired() bool {
+	if tw.timedOut {
+		return true
+	}
+	if tw.ctx.Err() != context.DeadlineExceeded {
+		return false
+	}
+	tw.writeTimeout()
+
+	return true
+}
+
+func (tw *timeoutWriter) writeTimeout() {
+	tw.timedOut = true
+
+	body := http.StatusText(tw.status)
+	h := tw.w.Header()
+	h.Set("Content-Type", "text/plain; charset=utf-8")
+	h.Set("Content-Length", strconv.Itoa(len(body)))
+	tw.w.WriteHeader(tw.status)
+	_, _ = tw.w.Write([]byte(body))
+}
+
+// finish prevents any later timeout response and tells if one was written,
+// the headers of a handler that returned without writing are sent with a 200
+func (tw *timeoutWriter) finish() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if !tw.done && !tw.wroteHeader && !tw.expired() {
+		tw.writeHeader(http.StatusOK)
+	}
+	tw.done = true
+
+	return tw.timedOut
+}
@@ -0,0 +1,142 @@
+package gohttpmw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeout(t *testing.T) {
+	tests := []struct {
+		name           string
+		handler        http.HandlerFunc
+		opts           []TimeoutOption
+		expectedStatus int
+		expectedBody   string
+		expectedHeader string
+		expectedErr    error
+	}{
+		{
+			name: "fast handler",
+			handler: http.HandlerFunc(
+				func(w http.ResponseWriter, req *http.Request) {
+					w.Header().Set("X-Test", "test")
+					_, _ = w.Write([]byte("ok"))
+				}),
+			expectedStatus: http.StatusOK,
+			expectedBody:   "ok",
+			expectedHeader: "test",
+		},
+		{
+			name: "handler only setting headers",
+			handler: http.HandlerFunc(
+				func(w http.ResponseWriter, req *http.Request) {
+					w.Header().Set("X-Test", "test")
+				}),
+			expectedStatus: http.StatusOK,
+			expectedHeader: "test",
+		},
+		{
+			name: "slow handler",
+			handler: http.HandlerFunc(
+				func(w http.ResponseWriter, req *http.Request) {
+					<-req.Context().Done()
+					w.Header().Set("X-Test", "test")
+					if _, err := w.Write([]byte("late")); err != http.ErrHandlerTimeout {
+						t.Errorf("expected %v on late write, got %v", http.ErrHandlerTimeout, err)
+					}
+				}),
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   http.StatusText(http.StatusServiceUnavailable),
+			expectedErr:    ErrRequestTimeout,
+		},
+		{
+			name: "slow handler with custom status",
+			handler: http.HandlerFunc(
+				func(w http.ResponseWriter, req *http.Request) {
+					<-req.Context().Done()
+				}),
+			opts:           []TimeoutOption{WithTimeoutStatus(http.StatusGatewayTimeout)},
+			expectedStatus: http.StatusGatewayTimeout,
+			expectedBody:   http.StatusText(http.StatusGatewayTimeout),
+			expectedErr:    ErrRequestTimeout,
+		},
+		{
+			name: "slow handler that already responded",
+			handler: http.HandlerFunc(
+				func(w http.ResponseWriter, req *http.Request) {
+					w.WriteHeader(http.StatusAccepted)
+					<-req.Context().Done()
+					_, _ = w.Write([]byte("done"))
+				}),
+			expectedStatus: http.StatusAccepted,
+			expectedBody:   "done",
+		},
+		{
+			name: "route without timeout",
+			handler: http.HandlerFunc(
+				func(w http.ResponseWriter, req *http.Request) {
+					if _, ok := req.Context().Deadline(); ok {
+						t.Errorf("expected no deadline")
+					}
+				}),
+			opts: []TimeoutOption{WithRouteTimeout(
+				func(*http.Request) time.Duration { return -1 },
+			)},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			midWared := Timeout(20*time.Millisecond, tt.opts...)(tt.handler)
+			rr := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, `/`, nil)
+			midWared.ServeHTTP(rr, request)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if body := rr.Body.String(); body != tt.expectedBody {
+				t.Errorf("expected body %q, got %q", tt.expectedBody, body)
+			}
+			if err := GetRequestError(request.Context()); err != tt.expectedErr {
+				t.Errorf("expected request error %v, got %v", tt.expectedErr, err)
+			}
+			if h := rr.Header().Get("X-Test"); h != tt.expectedHeader {
+				t.Errorf("expected header X-Test %q, got %q", tt.expectedHeader, h)
+			}
+		})
+	}
+}
+
+func TestTimeoutPerRoute(t *testing.T) {
+	fakeHandler := http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			deadline, _ := req.Context().Deadline()
+			_, _ = w.Write([]byte(time.Until(deadline).Round(time.Minute).String()))
+		},
+	)
+	midWared := Timeout(
+		time.Minute,
+		WithRouteTimeout(func(r *http.Request) time.Duration {
+			if strings.HasPrefix(r.URL.Path, "/reports") {
+				return 10 * time.Minute
+			}
+			return 0
+		}),
+	)(fakeHandler)
+
+	for path, expected := range map[string]string{
+		"/reports/1": "10m0s",
+		"/users":     "1m0s",
+	} {
+		rr := httptest.NewRecorder()
+		midWared.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		if rr.Body.String() != expected {
+			t.Errorf("%s: expected deadline in %s, got %s", path, expected, rr.Body.String())
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package gohttpmw
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/vincentserpoul/gohttpmw"
+
+// TracingOption configures the Tracing middleware
+type TracingOption func(*tracingConfig)
+
+type tracingConfig struct {
+	propagator propagation.TextMapPropagator
+	spanName   func(*http.Request) string
+}
+
+// WithPropagator sets the propagator used to extract the inbound
+// trace context, W3C trace context by default
+func WithPropagator(p propagation.TextMapPropagator) TracingOption {
+	return func(c *tracingConfig) {
+		c.propagator = p
+	}
+}
+
+// WithSpanName sets the name of the server spans, the method by default.
+// Like the metrics route label it must have a bounded set of values
+func WithSpanName(f func(*http.Request) string) TracingOption {
+	return func(c *tracingConfig) {
+		c.spanName = f
+	}
+}
+
+// Tracing starts a server span for each request, continuing the trace
+// of the inbound headers. The request error, if any, is recorded on the span
+// and the trace and span ids are picked up by Logger and LoggerZero
+func Tracing(
+	tp trace.TracerProvider,
+	opts ...TracingOption,
+) func(http.Handler) http.Handler {
+	cfg := tracingConfig{
+		propagator: propagation.TraceContext{},
+		spanName:   func(r *http.Request) string { return r.Method },
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	tracer := tp.Tracer(tracerName)
+
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := cfg.propagator.Extract(
+				r.Context(), propagation.HeaderCarrier(r.Header),
+			)
+
+			ctx, span := tracer.Start(
+				ctx,
+				cfg.spanName(r),
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("http.request.method", r.Method),
+					attribute.String("url.scheme", requestScheme(r)),
+					attribute.String("url.path", r.URL.Path),
+					attribute.String("server.address", requestHost(r)),
+					attribute.String("client.address", clientIP(r)),
+					attribute.String("user_agent.original", r.UserAgent()),
+					attribute.String("network.protocol.version", protoVersion(r)),
+				),
+			)
+			defer span.End()
+
+			if reqID := GetRequestID(ctx); reqID != "" {
+				span.SetAttributes(attribute.String("request_id", reqID))
+			}
+
+			naw := newAugmentedResponseWriter(w)
+			// We don't want to lose the reference to the Request
+			*r = *r.WithContext(ctx)
+			h.ServeHTTP(naw, r)
+
+			span.SetAttributes(
+				attribute.Int("http.response.status_code", naw.httpStatus),
+				attribute.Int("http.response.body.size", naw.length),
+			)
+
+			reqErr := GetRequestError(r.Context())
+			if reqErr != nil {
+				span.RecordError(reqErr)
+			}
+			// Only server errors are span errors for server spans
+			if naw.httpStatus >= http.StatusInternalServerError {
+				desc := http.StatusText(naw.httpStatus)
+				if reqErr != nil {
+					desc = reqErr.Error()
+				}
+				span.SetStatus(codes.Error, desc)
+			}
+		})
+	}
+}
+
+// traceIDs returns the trace and span ids of the span in ctx, if any
+func traceIDs(ctx context.Context) (string, string, bool) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return "", "", false
+	}
+
+	return sc.TraceID().String(), sc.SpanID().String(), true
+}
+
+func protoVersion(r *http.Request) string {
+	if r.ProtoMajor == 2 || r.ProtoMajor == 3 {
+		return strconv.Itoa(r.ProtoMajor)
+	}
+
+	return strconv.Itoa(r.ProtoMajor) + "." + strconv.Itoa(r.ProtoMinor)
+}
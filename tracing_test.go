@@ -0,0 +1,172 @@
+package gohttpmw
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/sirupsen/logrus/hooks/test"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracing(t *testing.T) {
+	errTest := errors.New("test error")
+
+	tests := []struct {
+		name               string
+		handler            http.HandlerFunc
+		traceparent        string
+		expectedStatus     int
+		expectedSpanStatus codes.Code
+		expectedEvents     int
+	}{
+		{
+			name: "new trace",
+			handler: http.HandlerFunc(
+				func(w http.ResponseWriter, req *http.Request) {
+					w.WriteHeader(http.StatusOK)
+				}),
+			expectedStatus:     http.StatusOK,
+			expectedSpanStatus: codes.Unset,
+		},
+		{
+			name: "inbound trace context",
+			handler: http.HandlerFunc(
+				func(w http.ResponseWriter, req *http.Request) {
+					w.WriteHeader(http.StatusOK)
+				}),
+			traceparent:        "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			expectedStatus:     http.StatusOK,
+			expectedSpanStatus: codes.Unset,
+		},
+		{
+			name: "client error recorded but not a span error",
+			handler: http.HandlerFunc(
+				func(w http.ResponseWriter, req *http.Request) {
+					SetRequestError(req, errTest)
+					w.WriteHeader(http.StatusBadRequest)
+				}),
+			expectedStatus:     http.StatusBadRequest,
+			expectedSpanStatus: codes.Unset,
+			expectedEvents:     1,
+		},
+		{
+			name: "server error",
+			handler: http.HandlerFunc(
+				func(w http.ResponseWriter, req *http.Request) {
+					SetRequestError(req, errTest)
+					w.WriteHeader(http.StatusInternalServerError)
+				}),
+			expectedStatus:     http.StatusInternalServerError,
+			expectedSpanStatus: codes.Error,
+			expectedEvents:     1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+			midWared := RequestID()(Tracing(tp)(tt.handler))
+			rr := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, `/test`, nil)
+			if tt.traceparent != "" {
+				request.Header.Set("traceparent", tt.traceparent)
+			}
+			midWared.ServeHTTP(rr, request)
+
+			spans := exporter.GetSpans()
+			if len(spans) != 1 {
+				t.Fatalf("expected 1 span, got %d", len(spans))
+			}
+			span := spans[0]
+
+			if span.SpanKind != trace.SpanKindServer || span.Name != http.MethodGet {
+				t.Errorf("unexpected span %s of kind %v", span.Name, span.SpanKind)
+			}
+			if tt.traceparent != "" &&
+				span.SpanContext.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+				t.Errorf(
+					"expected the inbound trace to be continued, got %s",
+					span.SpanContext.TraceID(),
+				)
+			}
+			if span.Status.Code != tt.expectedSpanStatus {
+				t.Errorf(
+					"expected span status %v, got %v",
+					tt.expectedSpanStatus, span.Status.Code,
+				)
+			}
+			if len(span.Events) != tt.expectedEvents {
+				t.Errorf(
+					"expected %d span events, got %d",
+					tt.expectedEvents, len(span.Events),
+				)
+			}
+
+			var status, reqID attribute.Value
+			for _, a := range span.Attributes {
+				switch a.Key {
+				case "http.response.status_code":
+					status = a.Value
+				case "request_id":
+					reqID = a.Value
+				}
+			}
+			if reqID.AsString() == "" || reqID.AsString() != rr.Header().Get("requestID") {
+				t.Errorf("expected the request id attribute, got %q", reqID.AsString())
+			}
+			if status.AsInt64() != int64(tt.expectedStatus) {
+				t.Errorf(
+					"expected status attribute %d, got %v",
+					tt.expectedStatus, status.Emit(),
+				)
+			}
+		})
+	}
+}
+
+func TestTracingLoggers(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	fakeHandler := http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {},
+	)
+
+	logger, hook := test.NewNullLogger()
+	Logger(logger)(Tracing(tp)(fakeHandler)).ServeHTTP(
+		httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, `/`, nil),
+	)
+
+	out := &bytes.Buffer{}
+	LoggerZero(zerolog.New(out))(Tracing(tp)(fakeHandler)).ServeHTTP(
+		httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, `/`, nil),
+	)
+	logRes := make(map[string]interface{})
+	if err := json.Unmarshal(out.Bytes(), &logRes); err != nil {
+		t.Fatalf("error unmarshalling log %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+
+	if hook.LastEntry().Data["trace_id"] != spans[0].SpanContext.TraceID().String() ||
+		hook.LastEntry().Data["span_id"] != spans[0].SpanContext.SpanID().String() {
+		t.Errorf("expected trace ids in logrus log, got %v", hook.LastEntry().Data)
+	}
+	if logRes["trace_id"] != spans[1].SpanContext.TraceID().String() ||
+		logRes["span_id"] != spans[1].SpanContext.SpanID().String() {
+		t.Errorf("expected trace ids in zerolog log, got %v", logRes)
+	}
+}